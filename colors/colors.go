@@ -1,8 +1,114 @@
 package colors
 
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
 const (
 	Reset = "\033[0m"
 	OK    = "\033[0;32m"
 	Error = "\033[0;31m"
 	Warn  = "\033[0;33m"
 )
+
+// Mode is the setting of the --color flag.
+type Mode int
+
+const (
+	Auto Mode = iota
+	Always
+	Never
+)
+
+type InvalidModeError struct {
+	Mode string
+}
+
+func (e *InvalidModeError) Error() string {
+	return fmt.Sprintf("invalid color mode %q; use auto, always or never", e.Mode)
+}
+
+func ParseMode(s string) (Mode, error) {
+	switch s {
+	case "auto", "":
+		return Auto, nil
+	case "always":
+		return Always, nil
+	case "never":
+		return Never, nil
+	}
+	return Auto, &InvalidModeError{Mode: s}
+}
+
+// Enabled reports whether output written to w should be colored. In Auto
+// mode, output is colored only if w is a terminal and NO_COLOR is not set.
+func (m Mode) Enabled(w io.Writer) bool {
+	switch m {
+	case Always:
+		return true
+	case Never:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return IsTerminal(w)
+}
+
+// IsTerminal reports whether w is a file connected to a terminal.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Writer writes to an underlying writer, and prints messages in color if
+// enabled. Everything else written to it is passed through unchanged.
+type Writer struct {
+	w       io.Writer
+	enabled bool
+}
+
+func NewWriter(w io.Writer, enabled bool) *Writer {
+	return &Writer{w: w, enabled: enabled}
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	return w.w.Write(p)
+}
+
+// Raw returns the underlying writer, e.g. to hand a terminal to a child
+// process.
+func (w *Writer) Raw() io.Writer {
+	return w.w
+}
+
+// OK prints msg on its own line in green.
+func (w *Writer) OK(msg string) {
+	w.print(OK, msg)
+}
+
+// Warn prints msg on its own line in yellow.
+func (w *Writer) Warn(msg string) {
+	w.print(Warn, msg)
+}
+
+// Error prints msg on its own line in red.
+func (w *Writer) Error(msg string) {
+	w.print(Error, msg)
+}
+
+func (w *Writer) print(color string, msg string) {
+	if w.enabled {
+		msg = color + msg + Reset
+	}
+	io.WriteString(w.w, msg+"\n")
+}
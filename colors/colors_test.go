@@ -0,0 +1,78 @@
+package colors_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/nitrous-io/goop/colors"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "colors")
+}
+
+var _ = Describe("colors", func() {
+	Describe("ParseMode()", func() {
+		It("parses --color values", func() {
+			Expect(colors.ParseMode("always")).To(Equal(colors.Always))
+			Expect(colors.ParseMode("never")).To(Equal(colors.Never))
+			Expect(colors.ParseMode("auto")).To(Equal(colors.Auto))
+		})
+
+		It("fails for anything else", func() {
+			_, err := colors.ParseMode("sometimes")
+			Expect(err).To(BeAssignableToTypeOf(&colors.InvalidModeError{}))
+		})
+	})
+
+	Describe("Mode.Enabled()", func() {
+		var noColor string
+
+		BeforeEach(func() {
+			noColor = os.Getenv("NO_COLOR")
+			os.Setenv("NO_COLOR", "")
+		})
+
+		AfterEach(func() {
+			os.Setenv("NO_COLOR", noColor)
+		})
+
+		It("does not color output that is not a terminal", func() {
+			Expect(colors.Auto.Enabled(&bytes.Buffer{})).To(BeFalse())
+			Expect(colors.Always.Enabled(&bytes.Buffer{})).To(BeTrue())
+		})
+
+		It("honors NO_COLOR unless colors are forced", func() {
+			os.Setenv("NO_COLOR", "1")
+			Expect(colors.Auto.Enabled(os.Stdout)).To(BeFalse())
+			Expect(colors.Always.Enabled(os.Stdout)).To(BeTrue())
+			Expect(colors.Never.Enabled(os.Stdout)).To(BeFalse())
+		})
+	})
+
+	Describe("Writer", func() {
+		var buf *bytes.Buffer
+
+		BeforeEach(func() {
+			buf = &bytes.Buffer{}
+		})
+
+		It("prints colored messages when enabled", func() {
+			w := colors.NewWriter(buf, true)
+			w.OK("=> Done!")
+			w.Write([]byte("plain\n"))
+			Expect(buf.String()).To(Equal(colors.OK + "=> Done!" + colors.Reset + "\nplain\n"))
+		})
+
+		It("prints plain messages when disabled", func() {
+			w := colors.NewWriter(buf, false)
+			w.Warn("Warning: careful")
+			w.Error("oops")
+			Expect(buf.String()).To(Equal("Warning: careful\noops\n"))
+		})
+	})
+})
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/nitrous-io/goop/goop"
+	"github.com/nitrous-io/goop/pkg/env"
+)
+
+// A Command is a goop subcommand, such as goop install.
+type Command struct {
+	// Run runs the command with the arguments left after flag parsing.
+	Run func(cmd *Command, g *goop.Goop, args []string) error
+
+	// UsageLine is the one-line usage message; its first word is the
+	// command name.
+	UsageLine string
+
+	// Short is the description shown in the 'goop help' output.
+	Short string
+
+	// Long is the description shown in the 'goop help <command>' output.
+	Long string
+
+	// Flag is the set of flags specific to this command.
+	Flag flag.FlagSet
+}
+
+func (c *Command) Name() string {
+	name := c.UsageLine
+	if i := strings.Index(name, " "); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// UsageError is returned by Run for invalid arguments.
+type UsageError struct {
+	Message string
+}
+
+func (e *UsageError) Error() string {
+	return e.Message
+}
+
+var commands = []*Command{
+	cmdInstall,
+	cmdUpdate,
+	cmdClean,
+	cmdEnv,
+	cmdGraph,
+	cmdWhy,
+	cmdList,
+	cmdShow,
+	cmdDiff,
+	cmdExec,
+	cmdShell,
+	cmdRun,
+	cmdGo,
+	cmdConfig,
+}
+
+func lookupCommand(name string) *Command {
+	for _, cmd := range commands {
+		if cmd.Name() == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+var cmdInstall = &Command{
+	Run: func(cmd *Command, g *goop.Goop, args []string) error {
+		if len(args) > 0 {
+			return &UsageError{Message: "install takes no arguments"}
+		}
+		return g.Install()
+	},
+	UsageLine: "install",
+	Short:     "install the dependencies specified by Goopfile or Goopfile.lock",
+	Long: `
+Install installs the dependencies listed in Goopfile.lock into the vendor
+directory. If there is no Goopfile.lock, the dependencies in Goopfile are
+installed and Goopfile.lock is written recording the exact revision of each
+package and its sub-dependencies. A Goopfile.lock in an older format is
+installed from as it is; run 'goop update' to upgrade it.
+`,
+}
+
+var cmdUpdate = &Command{
+	Run: func(cmd *Command, g *goop.Goop, args []string) error {
+		if len(args) > 0 {
+			return &UsageError{Message: "update takes no arguments"}
+		}
+		return g.Update()
+	},
+	UsageLine: "update",
+	Short:     "update dependencies to their latest versions",
+	Long: `
+Update ignores an existing Goopfile.lock, installs the dependencies in
+Goopfile at the latest revisions it allows, and rewrites Goopfile.lock.
+`,
+}
+
+var (
+	envShell string
+	envUnset bool
+)
+
+var cmdEnv = &Command{
+	Run: func(cmd *Command, g *goop.Goop, args []string) error {
+		if len(args) > 0 {
+			return &UsageError{Message: "env takes no arguments"}
+		}
+		err := g.PrintEnv(envShell, envUnset)
+		if _, ok := err.(*env.UnsupportedShellError); ok {
+			return &UsageError{Message: err.Error()}
+		}
+		return err
+	},
+	UsageLine: "env [--shell=bash|zsh|fish|powershell|json] [--unset]",
+	Short:     "print GOPATH, GOBIN and PATH environment variables, with the vendor path prepended",
+	Long: `
+Env prints statements that set GOPATH, GOBIN and PATH to use the vendor
+directory, so that running 'eval "$(goop env)"' sets up the current shell to
+use the installed dependencies.
+
+The --shell flag selects the syntax of the statements. It defaults to the
+shell named by $SHELL, or bash if that is not one of bash, zsh or fish. For
+fish, run 'goop env --shell=fish | source'; for PowerShell, run
+'goop env --shell=powershell | Invoke-Expression'. The json format prints an
+object for other tools to consume.
+
+The --unset flag prints statements that restore the previous values instead.
+`,
+}
+
+var graphFormat string
+
+var cmdGraph = &Command{
+	Run: func(cmd *Command, g *goop.Goop, args []string) error {
+		if len(args) > 0 {
+			return &UsageError{Message: "graph takes no arguments"}
+		}
+		return g.PrintGraph(graphFormat)
+	},
+	UsageLine: "graph [--format=tree|dot|json]",
+	Short:     "print the dependency graph recorded in Goopfile.lock",
+	Long: `
+Graph prints which Goopfile entry introduced each sub-dependency, as recorded
+in Goopfile.lock.
+
+The --format flag selects the output: an indented tree (the default), a
+Graphviz digraph (dot) or JSON.
+`,
+}
+
+var cmdWhy = &Command{
+	Run: func(cmd *Command, g *goop.Goop, args []string) error {
+		if len(args) != 1 {
+			return &UsageError{Message: "why takes exactly one package"}
+		}
+		return g.Why(args[0])
+	},
+	UsageLine: "why package",
+	Short:     "explain which Goopfile entries require a package",
+	Long: `
+Why prints every chain of dependencies from a Goopfile entry down to the given
+package, along with the revision each one is locked at.
+`,
+}
+
+var cmdList = &Command{
+	Run: func(cmd *Command, g *goop.Goop, args []string) error {
+		if len(args) > 0 {
+			return &UsageError{Message: "list takes no arguments"}
+		}
+		return g.List()
+	},
+	UsageLine: "list",
+	Short:     "list the packages in Goopfile.lock",
+	Long: `
+List prints every package in Goopfile.lock with its locked revision, version
+control system and repository URL, and whether it is listed in the Goopfile
+(direct) or was pulled in by another package (transitive).
+`,
+}
+
+var cmdShow = &Command{
+	Run: func(cmd *Command, g *goop.Goop, args []string) error {
+		if len(args) != 1 {
+			return &UsageError{Message: "show takes exactly one package"}
+		}
+		return g.Show(args[0])
+	},
+	UsageLine: "show package",
+	Short:     "show the details of a locked package",
+	Long: `
+Show prints where a package from Goopfile.lock is checked out, the checked out
+revision against the locked one, its last commit, and the Goopfile line that
+requires it, or the packages that pulled it in.
+`,
+}
+
+var cmdDiff = &Command{
+	Run: func(cmd *Command, g *goop.Goop, args []string) error {
+		if len(args) > 2 {
+			return &UsageError{Message: "diff takes at most two lock files or revisions"}
+		}
+		args = append(args, "", "")
+		return g.Diff(args[0], args[1])
+	},
+	UsageLine: "diff [old] [new]",
+	Short:     "show what changed between two versions of Goopfile.lock",
+	Long: `
+Diff prints the packages added (+), removed (-) and changed (~) between two
+versions of Goopfile.lock. For each changed package it also prints the commits
+between the old and new revision, read from the cached repository or the
+checkout in the vendor directory: commits only in the new revision are marked
++, and commits only in the old revision are marked -.
+
+Each of old and new is the path of a lock file or a git revision of the
+project's Goopfile.lock. Old defaults to HEAD and new to the Goopfile.lock in
+the project directory, so that "goop diff" shows uncommitted lock changes and
+"goop diff HEAD~1 HEAD" shows those of the last commit.
+`,
+}
+
+var (
+	execAutoInstall bool
+	execIsolated    bool
+	envFiles        stringsFlag
+)
+
+// stringsFlag is a flag that can be given more than once.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+var cmdExec = &Command{
+	Run: func(cmd *Command, g *goop.Goop, args []string) error {
+		if len(args) < 1 {
+			return &UsageError{Message: "missing command to execute"}
+		}
+		applyExecFlags(cmd, g)
+		return g.Exec(args[0], args[1:]...)
+	},
+	UsageLine: "exec [--auto-install] [--isolated] [--env-file file]... command [arguments]",
+	Short:     "execute a command in the context of the installed dependencies",
+	Long: `
+Exec runs the given command with GOPATH, GOBIN and PATH set up to use the
+installed dependencies. Commands installed in the vendor directory take
+precedence over those in PATH.
+
+Exec refuses to run the command if the vendor directory does not match
+Goopfile.lock, because install has not been run since the lock changed or
+was interrupted. With the --auto-install flag, or auto_install = true in the
+configuration, it runs install first instead.
+
+By default the vendor directory is prepended to your GOPATH, so packages in
+your own GOPATH are still found. With the --isolated flag, or isolated = true
+in the configuration, GOPATH only contains the vendor directory and, if the
+project lives in a GOPATH, a link to the project under its import path in
+vendor/self, so that packages missing from the Goopfile fail to build.
+
+The --env-file flag loads variables from a dotenv file of KEY=value lines,
+relative to the project directory. It can be given more than once, with
+later files taking precedence over earlier ones; variables already set in
+the environment take precedence over all of them.
+`,
+}
+
+var cmdShell = &Command{
+	Run: func(cmd *Command, g *goop.Goop, args []string) error {
+		if len(args) > 0 {
+			return &UsageError{Message: "shell takes no arguments"}
+		}
+		applyExecFlags(cmd, g)
+		return g.Shell()
+	},
+	UsageLine: "shell [--auto-install] [--isolated] [--env-file file]...",
+	Short:     "start a shell in the context of the installed dependencies",
+	Long: `
+Shell starts $SHELL with GOPATH, GOBIN and PATH set up as for 'goop exec',
+and GOOP_ACTIVE set to the project directory so that your prompt can show
+it. Exit the shell to return to the original environment. Shell refuses
+to start inside another goop shell, or after eval "$(goop env)". The
+--auto-install, --isolated and --env-file flags work as for 'goop exec'.
+`,
+}
+
+var cleanDryRun bool
+
+var cmdClean = &Command{
+	Run: func(cmd *Command, g *goop.Goop, args []string) error {
+		if len(args) > 0 {
+			return &UsageError{Message: "clean takes no arguments"}
+		}
+		return g.Clean(cleanDryRun)
+	},
+	UsageLine: "clean [--dry-run]",
+	Short:     "remove packages that are not in Goopfile.lock from the vendor directory",
+	Long: `
+Clean removes the repositories in the vendor directory that are no longer in
+Goopfile.lock, such as dependencies removed from the Goopfile, so that they
+cannot satisfy imports by accident. Their compiled packages and the commands
+built from them are removed too.
+
+The --dry-run flag prints what would be removed without removing anything.
+`,
+}
+
+var cmdRun = &Command{
+	Run: func(cmd *Command, g *goop.Goop, args []string) error {
+		if len(args) < 1 {
+			return g.PrintScripts()
+		}
+		applyExecFlags(cmd, g)
+		return g.Run(args[0], args[1:]...)
+	},
+	UsageLine: "run [--auto-install] [--isolated] [--env-file file]... [script [arguments]]",
+	Short:     "run a script from the Goopfile in the context of the installed dependencies",
+	Long: `
+Run runs a script defined in the Goopfile with sh, in the same environment as
+'goop exec'. Scripts are defined on lines of the form
+
+    script test: ginkgo -r
+
+Any arguments are appended to the script's command, so 'goop run test -v'
+runs 'ginkgo -r -v'. With no arguments, run lists the scripts. The
+--auto-install, --isolated and --env-file flags work as for 'goop exec'.
+`,
+}
+
+var cmdGo = &Command{
+	Run: func(cmd *Command, g *goop.Goop, args []string) error {
+		if len(args) < 1 {
+			return &UsageError{Message: "missing go command"}
+		}
+		applyExecFlags(cmd, g)
+		return g.Exec("go", args...)
+	},
+	UsageLine: "go [--auto-install] [--isolated] command [arguments]",
+	Short:     "execute a go command in the context of the installed dependencies",
+	Long: `
+Go is shorthand for 'goop exec go'. The --auto-install and --isolated flags
+go before the go command, and work as for 'goop exec'.
+`,
+}
+
+var cmdConfig = &Command{
+	Run: func(cmd *Command, g *goop.Goop, args []string) error {
+		if len(args) > 0 {
+			return &UsageError{Message: "config takes no arguments"}
+		}
+		return g.PrintConfig()
+	},
+	UsageLine: "config",
+	Short:     "print the effective configuration and where each setting comes from",
+	Long: `
+Config prints the settings in effect, merged from ~/.goop/config, the
+project's .goop.toml, GOOP_* environment variables and command line flags,
+noting where each one was set.
+`,
+}
+
+// applyExecFlags applies the flags shared by the commands that run in the
+// context of the installed dependencies. --auto-install and --isolated only
+// override the configuration when they are given.
+func applyExecFlags(cmd *Command, g *goop.Goop) {
+	cmd.Flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "auto-install":
+			g.SetAutoInstall(execAutoInstall)
+		case "isolated":
+			g.SetIsolated(execIsolated)
+		}
+	})
+	g.SetEnvFiles(envFiles)
+}
+
+// defaultShell returns the shell named by $SHELL if goop env supports it.
+func defaultShell() string {
+	switch sh := path.Base(os.Getenv("SHELL")); sh {
+	case "bash", "zsh", "fish":
+		return sh
+	}
+	return "bash"
+}
+
+func init() {
+	cmdEnv.Flag.StringVar(&envShell, "shell", defaultShell(), "")
+	cmdEnv.Flag.BoolVar(&envUnset, "unset", false, "")
+	cmdClean.Flag.BoolVar(&cleanDryRun, "dry-run", false, "")
+	for _, cmd := range []*Command{cmdExec, cmdShell, cmdRun, cmdGo} {
+		cmd.Flag.BoolVar(&execAutoInstall, "auto-install", false, "")
+		cmd.Flag.BoolVar(&execIsolated, "isolated", false, "")
+	}
+	for _, cmd := range []*Command{cmdExec, cmdShell, cmdRun} {
+		cmd.Flag.Var(&envFiles, "env-file", "")
+	}
+	cmdGraph.Flag.StringVar(&graphFormat, "format", "tree", "")
+}
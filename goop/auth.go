@@ -0,0 +1,380 @@
+package goop
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nitrous-io/goop/pkg/env"
+)
+
+// Credential holds the secrets used to fetch repositories from a host. Token
+// is sent over HTTPS; SSHKey is used for SSH URLs.
+type Credential struct {
+	Host     string
+	Username string
+	Token    string
+	SSHKey   string
+}
+
+// Credentials maps hosts to their credentials.
+type Credentials map[string]*Credential
+
+type CredentialsParseError struct {
+	File     string
+	LineNum  uint
+	LineText string
+}
+
+func (e *CredentialsParseError) Error() string {
+	return fmt.Sprintf("%s: parse failed at line %d - %s", e.File, e.LineNum, e.LineText)
+}
+
+type AuthError struct {
+	URL string
+	Err error
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("authentication failed for %s (%s); configure credentials for %s in ~/.goop/credentials, GOOP_CREDENTIALS or ~/.netrc", e.URL, e.Err, urlHost(e.URL))
+}
+
+type RepoNotFoundError struct {
+	URL string
+	Err error
+}
+
+func (e *RepoNotFoundError) Error() string {
+	return fmt.Sprintf("repository %s not found, or you do not have access to it (%s)", e.URL, e.Err)
+}
+
+var (
+	authFailureRe  = regexp.MustCompile(`(?i)authentication failed|could not read (username|password)|permission denied \(publickey|access denied|authorization failed|terminal prompts disabled|http error 401|http error 403`)
+	repoNotFoundRe = regexp.MustCompile(`(?i)repository.* not found|does not appear to be a git repository|does not exist|http error 404`)
+	scpLikeURLRe   = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):`)
+)
+
+// LoadCredentials reads credentials from ~/.netrc, ~/.goop/credentials and
+// the GOOP_CREDENTIALS environment variable, in increasing order of precedence.
+func LoadCredentials() (Credentials, error) {
+	home := os.Getenv("HOME")
+	creds := Credentials{}
+
+	netrc := os.Getenv("NETRC")
+	if netrc == "" {
+		netrc = path.Join(home, ".netrc")
+	}
+	c, err := readCredentialsFile(netrc, ParseNetrc)
+	if err != nil {
+		return nil, err
+	}
+	creds.Merge(c)
+
+	c, err = readCredentialsFile(path.Join(home, ".goop", "credentials"), ParseCredentials)
+	if err != nil {
+		return nil, err
+	}
+	creds.Merge(c)
+
+	c, err = CredentialsFromEnv(env.NewEnv())
+	if err != nil {
+		return nil, err
+	}
+	creds.Merge(c)
+	return creds, nil
+}
+
+func readCredentialsFile(filename string, parse func(io.Reader) (Credentials, error)) (Credentials, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Credentials{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	creds, err := parse(f)
+	if perr, ok := err.(*CredentialsParseError); ok {
+		perr.File = filename
+	}
+	return creds, err
+}
+
+// ParseCredentials parses a goop credentials file. Each line names a host
+// followed by key=value pairs, e.g.
+//
+//	github.com username=x-access-token token=abc123
+//	git.example.com ssh_key=~/.ssh/id_example
+func ParseCredentials(r io.Reader) (Credentials, error) {
+	s := bufio.NewScanner(r)
+	ln := uint(0)
+	creds := Credentials{}
+
+	for s.Scan() {
+		ln++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		tokens := strings.Fields(line)
+		c := &Credential{Host: tokens[0]}
+		for _, t := range tokens[1:] {
+			if strings.HasPrefix(t, "//") {
+				break
+			}
+			kv := strings.SplitN(t, "=", 2)
+			if len(kv) != 2 {
+				return nil, &CredentialsParseError{LineNum: ln, LineText: line}
+			}
+			switch kv[0] {
+			case "username":
+				c.Username = kv[1]
+			case "token", "password":
+				c.Token = kv[1]
+			case "ssh_key":
+				c.SSHKey = kv[1]
+			default:
+				return nil, &CredentialsParseError{LineNum: ln, LineText: line}
+			}
+		}
+		creds.Merge(Credentials{c.Host: c})
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// ParseNetrc parses the machine entries of a .netrc file.
+func ParseNetrc(r io.Reader) (Credentials, error) {
+	s := bufio.NewScanner(r)
+	s.Split(bufio.ScanWords)
+	creds := Credentials{}
+
+	var c *Credential
+	for s.Scan() {
+		switch s.Text() {
+		case "machine":
+			c = nil
+			if s.Scan() {
+				c = &Credential{Host: s.Text()}
+				creds[c.Host] = c
+			}
+		case "default":
+			c = nil
+		case "login":
+			if s.Scan() && c != nil {
+				c.Username = s.Text()
+			}
+		case "password":
+			if s.Scan() && c != nil {
+				c.Token = s.Text()
+			}
+		case "account":
+			s.Scan()
+		case "macdef":
+			// macros are not supported; stop parsing
+			return creds, s.Err()
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// CredentialsFromEnv reads credentials from GOOP_CREDENTIALS, which uses the
+// credentials file syntax with entries separated by newlines or semicolons.
+func CredentialsFromEnv(e env.Env) (Credentials, error) {
+	creds, err := ParseCredentials(strings.NewReader(strings.Replace(e["GOOP_CREDENTIALS"], ";", "\n", -1)))
+	if perr, ok := err.(*CredentialsParseError); ok {
+		perr.File = "GOOP_CREDENTIALS"
+	}
+	return creds, err
+}
+
+// Merge copies the non-empty fields of o over c.
+func (c Credentials) Merge(o Credentials) {
+	for host, oc := range o {
+		cc := c[host]
+		if cc == nil {
+			cc = &Credential{Host: host}
+			c[host] = cc
+		}
+		if oc.Username != "" {
+			cc.Username = oc.Username
+		}
+		if oc.Token != "" {
+			cc.Token = oc.Token
+		}
+		if oc.SSHKey != "" {
+			cc.SSHKey = oc.SSHKey
+		}
+	}
+}
+
+// ForURL returns the credentials for the host of a repository URL, or nil.
+func (c Credentials) ForURL(url string) *Credential {
+	return c[urlHost(url)]
+}
+
+// GitEnv adds the settings git needs to authenticate against url to e.
+// HTTPS tokens are sent as an Authorization header scoped to each host, so
+// they also apply to repositories cloned by go get.
+func (c Credentials) GitEnv(e env.Env, url string) {
+	count, _ := strconv.Atoi(e["GIT_CONFIG_COUNT"])
+	for _, host := range c.hosts() {
+		cred := c[host]
+		if cred.Token == "" {
+			continue
+		}
+		e["GIT_CONFIG_KEY_"+strconv.Itoa(count)] = "http.https://" + host + "/.extraheader"
+		e["GIT_CONFIG_VALUE_"+strconv.Itoa(count)] = "Authorization: Basic " + cred.basicAuth()
+		count++
+	}
+	if count > 0 {
+		e["GIT_CONFIG_COUNT"] = strconv.Itoa(count)
+	}
+
+	if cred := c.ForURL(url); cred != nil && cred.SSHKey != "" && isSSHURL(url) {
+		e["GIT_SSH_COMMAND"] = cred.sshCommand()
+	}
+}
+
+// HgEnv writes the settings hg needs to authenticate with HTTPS tokens to a
+// private hgrc file and adds it to HGRCPATH in e, after the usual hgrc files,
+// so that tokens never appear on the command line. It returns the path of
+// the file, which the caller removes once hg has run, or "" if no file was
+// needed.
+func (c Credentials) HgEnv(e env.Env) (string, error) {
+	buf := &bytes.Buffer{}
+	for i, host := range c.hosts() {
+		cred := c[host]
+		if cred.Token == "" {
+			continue
+		}
+		section := "goop" + strconv.Itoa(i)
+		fmt.Fprintf(buf, "%s.prefix = https://%s\n", section, host)
+		fmt.Fprintf(buf, "%s.username = %s\n", section, cred.username())
+		fmt.Fprintf(buf, "%s.password = %s\n", section, cred.Token)
+	}
+	if buf.Len() == 0 {
+		return "", nil
+	}
+
+	// ioutil.TempFile creates files readable only by their owner
+	f, err := ioutil.TempFile("", "goop-hgrc")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(append([]byte("[auth]\n"), buf.Bytes()...))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	rcPath := e["HGRCPATH"]
+	if _, ok := e["HGRCPATH"]; !ok {
+		rcPath = strings.Join([]string{"/etc/mercurial/hgrc", "/etc/mercurial/hgrc.d", path.Join(e["HOME"], ".hgrc"), path.Join(e["HOME"], ".config/hg/hgrc")}, string(os.PathListSeparator))
+	}
+	if rcPath != "" {
+		rcPath += string(os.PathListSeparator)
+	}
+	e["HGRCPATH"] = rcPath + f.Name()
+	return f.Name(), nil
+}
+
+// HgArgs returns the global options hg needs to authenticate against url
+// over SSH.
+func (c Credentials) HgArgs(url string) []string {
+	var args []string
+	if cred := c.ForURL(url); cred != nil && cred.SSHKey != "" && isSSHURL(url) {
+		args = append(args, "--ssh", cred.sshCommand())
+	}
+	return args
+}
+
+func (c Credentials) hosts() []string {
+	hosts := make([]string, 0, len(c))
+	for host := range c {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+func (c *Credential) username() string {
+	if c.Username == "" {
+		return "x-access-token"
+	}
+	return c.Username
+}
+
+func (c *Credential) basicAuth() string {
+	return base64.StdEncoding.EncodeToString([]byte(c.username() + ":" + c.Token))
+}
+
+func (c *Credential) sshCommand() string {
+	key := c.SSHKey
+	if strings.HasPrefix(key, "~/") {
+		key = path.Join(os.Getenv("HOME"), key[2:])
+	}
+	return "ssh -i " + strconv.Quote(key) + " -o IdentitiesOnly=yes"
+}
+
+// ClassifyVCSError turns the failure of a clone or fetch from url into an
+// *AuthError or *RepoNotFoundError when output says so.
+func ClassifyVCSError(url string, output string, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case authFailureRe.MatchString(output):
+		return &AuthError{URL: url, Err: err}
+	case repoNotFoundRe.MatchString(output):
+		return &RepoNotFoundError{URL: url, Err: err}
+	}
+	return err
+}
+
+func isSSHURL(url string) bool {
+	return strings.HasPrefix(url, "ssh://") || strings.HasPrefix(url, "git+ssh://") ||
+		(!strings.Contains(url, "://") && scpLikeURLRe.MatchString(url))
+}
+
+// urlHost returns the host of a repository URL, including scp-like SSH URLs
+// such as git@github.com:foo/bar.git.
+func urlHost(url string) string {
+	if i := strings.Index(url, "://"); i >= 0 {
+		rest := url[i+3:]
+		if j := strings.IndexAny(rest, "/"); j >= 0 {
+			rest = rest[:j]
+		}
+		if j := strings.LastIndex(rest, "@"); j >= 0 {
+			rest = rest[j+1:]
+		}
+		if j := strings.Index(rest, ":"); j >= 0 {
+			rest = rest[:j]
+		}
+		return rest
+	}
+	if m := scpLikeURLRe.FindStringSubmatch(url); m != nil {
+		return m[1]
+	}
+	return ""
+}
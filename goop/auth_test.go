@@ -0,0 +1,186 @@
+package goop_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/nitrous-io/goop/goop"
+	"github.com/nitrous-io/goop/pkg/env"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("auth", func() {
+	Describe("ParseCredentials()", func() {
+		It("parses host entries", func() {
+			creds, err := goop.ParseCredentials(bytes.NewBufferString(`
+				# tokens
+				github.com username=bot token=abc123 // CI bot
+				git.example.com ssh_key=~/.ssh/id_example
+			`))
+			Expect(err).To(BeNil())
+			Expect(creds).To(Equal(goop.Credentials{
+				"github.com":      {Host: "github.com", Username: "bot", Token: "abc123"},
+				"git.example.com": {Host: "git.example.com", SSHKey: "~/.ssh/id_example"},
+			}))
+		})
+
+		It("fails for unknown keys", func() {
+			_, err := goop.ParseCredentials(bytes.NewBufferString("github.com color=blue\n"))
+			Expect(err).To(BeAssignableToTypeOf(&goop.CredentialsParseError{}))
+		})
+	})
+
+	Describe("ParseNetrc()", func() {
+		It("parses machine entries", func() {
+			creds, err := goop.ParseNetrc(bytes.NewBufferString(`
+				machine github.com
+				  login bot
+				  password abc123
+				default login anonymous password guest
+			`))
+			Expect(err).To(BeNil())
+			Expect(creds).To(Equal(goop.Credentials{
+				"github.com": {Host: "github.com", Username: "bot", Token: "abc123"},
+			}))
+		})
+	})
+
+	Describe("CredentialsFromEnv()", func() {
+		It("parses GOOP_CREDENTIALS", func() {
+			creds, err := goop.CredentialsFromEnv(env.Env{"GOOP_CREDENTIALS": "github.com token=abc123; bitbucket.org token=def456"})
+			Expect(err).To(BeNil())
+			Expect(creds).To(HaveLen(2))
+			Expect(creds["bitbucket.org"].Token).To(Equal("def456"))
+		})
+	})
+
+	Describe("Credentials", func() {
+		var creds goop.Credentials
+
+		BeforeEach(func() {
+			creds = goop.Credentials{
+				"github.com":      {Host: "github.com", Username: "bot", Token: "abc123"},
+				"git.example.com": {Host: "git.example.com", SSHKey: "/keys/example"},
+			}
+		})
+
+		Describe("ForURL()", func() {
+			It("matches the host of https and ssh URLs", func() {
+				Expect(creds.ForURL("https://github.com/foo/bar.git").Token).To(Equal("abc123"))
+				Expect(creds.ForURL("git@git.example.com:foo/bar.git").SSHKey).To(Equal("/keys/example"))
+				Expect(creds.ForURL("ssh://git@git.example.com:22/foo/bar").SSHKey).To(Equal("/keys/example"))
+				Expect(creds.ForURL("https://bitbucket.org/foo/bar")).To(BeNil())
+			})
+		})
+
+		Describe("Merge()", func() {
+			It("overrides non-empty fields", func() {
+				creds.Merge(goop.Credentials{"github.com": {Host: "github.com", Token: "xyz"}})
+				Expect(creds["github.com"]).To(Equal(&goop.Credential{Host: "github.com", Username: "bot", Token: "xyz"}))
+			})
+		})
+
+		Describe("GitEnv()", func() {
+			It("adds an authorization header per host and the ssh key for the URL", func() {
+				e := env.Env{}
+				creds.GitEnv(e, "git@git.example.com:foo/bar.git")
+				Expect(e).To(Equal(env.Env{
+					"GIT_CONFIG_COUNT":   "1",
+					"GIT_CONFIG_KEY_0":   "http.https://github.com/.extraheader",
+					"GIT_CONFIG_VALUE_0": "Authorization: Basic Ym90OmFiYzEyMw==",
+					"GIT_SSH_COMMAND":    `ssh -i "/keys/example" -o IdentitiesOnly=yes`,
+				}))
+			})
+
+			It("appends to existing git config entries", func() {
+				e := env.Env{"GIT_CONFIG_COUNT": "2"}
+				creds.GitEnv(e, "https://github.com/foo/bar")
+				Expect(e["GIT_CONFIG_COUNT"]).To(Equal("3"))
+				Expect(e["GIT_CONFIG_KEY_2"]).To(Equal("http.https://github.com/.extraheader"))
+				Expect(e).NotTo(HaveKey("GIT_SSH_COMMAND"))
+			})
+		})
+
+		Describe("HgEnv()", func() {
+			It("writes auth settings to a private hgrc added to HGRCPATH", func() {
+				e := env.Env{"HGRCPATH": "/etc/hgrc"}
+				hgrc, err := creds.HgEnv(e)
+				Expect(err).To(BeNil())
+				defer os.Remove(hgrc)
+
+				Expect(e["HGRCPATH"]).To(Equal("/etc/hgrc:" + hgrc))
+				fi, err := os.Stat(hgrc)
+				Expect(err).To(BeNil())
+				Expect(fi.Mode().Perm()).To(Equal(os.FileMode(0600)))
+				b, err := ioutil.ReadFile(hgrc)
+				Expect(err).To(BeNil())
+				Expect(string(b)).To(Equal("[auth]\n" +
+					"goop1.prefix = https://github.com\n" +
+					"goop1.username = bot\n" +
+					"goop1.password = abc123\n"))
+			})
+
+			It("keeps reading the usual hgrc files", func() {
+				e := env.Env{"HOME": "/home/me"}
+				hgrc, err := creds.HgEnv(e)
+				Expect(err).To(BeNil())
+				defer os.Remove(hgrc)
+				Expect(e["HGRCPATH"]).To(Equal("/etc/mercurial/hgrc:/etc/mercurial/hgrc.d:/home/me/.hgrc:/home/me/.config/hg/hgrc:" + hgrc))
+			})
+
+			It("writes nothing without tokens", func() {
+				e := env.Env{}
+				hgrc, err := goop.Credentials{}.HgEnv(e)
+				Expect(err).To(BeNil())
+				Expect(hgrc).To(BeEmpty())
+				Expect(e).To(BeEmpty())
+			})
+		})
+
+		Describe("HgArgs()", func() {
+			It("keeps tokens off the command line", func() {
+				Expect(creds.HgArgs("https://github.com/foo/bar")).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("ClassifyVCSError()", func() {
+		exitErr := errors.New("exit status 128")
+
+		It("detects authentication failures", func() {
+			err := goop.ClassifyVCSError("https://github.com/foo/bar", "fatal: Authentication failed for 'https://github.com/foo/bar/'", exitErr)
+			Expect(err).To(BeAssignableToTypeOf(&goop.AuthError{}))
+			Expect(err.Error()).To(ContainSubstring("configure credentials for github.com"))
+
+			err = goop.ClassifyVCSError("git@github.com:foo/bar", "git@github.com: Permission denied (publickey).", exitErr)
+			Expect(err).To(BeAssignableToTypeOf(&goop.AuthError{}))
+		})
+
+		It("detects missing repositories", func() {
+			err := goop.ClassifyVCSError("https://github.com/foo/bar", "remote: Repository not found.\nfatal: repository 'https://github.com/foo/bar/' not found", exitErr)
+			Expect(err).To(BeAssignableToTypeOf(&goop.RepoNotFoundError{}))
+		})
+
+		It("passes through other errors", func() {
+			Expect(goop.ClassifyVCSError("https://github.com/foo/bar", "fatal: unable to access", exitErr)).To(Equal(exitErr))
+			Expect(goop.ClassifyVCSError("https://github.com/foo/bar", "", nil)).To(BeNil())
+		})
+
+		It("classifies the output of failed clones", func() {
+			dir := tempDir()
+			missing := path.Join(dir, "missing")
+			Expect(ioutil.WriteFile(path.Join(dir, "Goopfile"), []byte("github.com/nitrous-io/missing !"+missing+"\n"), 0644)).To(Succeed())
+			g := newTestGoop(dir, ioutil.Discard)
+			g.SetVerbosity(goop.Quiet)
+
+			err := g.Install()
+			Expect(err).To(BeAssignableToTypeOf(&goop.RepoNotFoundError{}))
+			Expect(err.(*goop.RepoNotFoundError).URL).To(Equal(missing))
+		})
+	})
+})
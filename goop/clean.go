@@ -0,0 +1,221 @@
+package goop
+
+import (
+	goparser "go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// vcsDirs mark the root of a repository.
+var vcsDirs = []string{".git", ".hg", ".bzr", ".svn"}
+
+// Clean removes the repositories under the vendor directory that are not in
+// Goopfile.lock, along with their compiled packages and the commands built
+// from them. With dryRun, it only prints what it would remove.
+func (g *Goop) Clean(dryRun bool) error {
+	lock, err := g.readLock()
+	if err != nil {
+		return err
+	}
+
+	locked := map[string]bool{}
+	for _, dep := range lock.Deps {
+		repo, err := g.lockedRepo(dep)
+		if err != nil {
+			return err
+		}
+		locked[repo.Root] = true
+	}
+
+	srcPath := path.Join(g.vendorDir(), "src")
+	roots, err := repoRoots(srcPath)
+	if err != nil {
+		return err
+	}
+
+	var orphans []string
+	keptCommands := map[string]bool{}
+	for _, root := range roots {
+		if locked[root] || containsLocked(root, locked) {
+			cmds, err := commandNames(path.Join(srcPath, root))
+			if err != nil {
+				return err
+			}
+			for _, c := range cmds {
+				keptCommands[c] = true
+			}
+			continue
+		}
+		orphans = append(orphans, root)
+	}
+
+	if len(orphans) == 0 {
+		g.progress("=> Nothing to clean")
+		return nil
+	}
+
+	binPath := path.Join(g.vendorDir(), "bin")
+	for _, root := range orphans {
+		// each path to remove, mapped to the directory its empty parents
+		// are removed up to
+		var remove []string
+		tops := map[string]string{}
+
+		cmds, err := commandNames(path.Join(srcPath, root))
+		if err != nil {
+			return err
+		}
+		for _, c := range cmds {
+			if !keptCommands[c] {
+				p := path.Join(binPath, c)
+				remove = append(remove, p)
+				tops[p] = binPath
+			}
+		}
+
+		for _, pattern := range []string{root, root + ".a"} {
+			archives, err := filepath.Glob(path.Join(g.vendorDir(), "pkg", "*", pattern))
+			if err != nil {
+				return err
+			}
+			for _, p := range archives {
+				remove = append(remove, p)
+				tops[p] = p[:len(p)-len(pattern)-1]
+			}
+		}
+
+		p := path.Join(srcPath, root)
+		remove = append(remove, p)
+		tops[p] = srcPath
+
+		for _, p := range remove {
+			exists, err := pathExists(p)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				continue
+			}
+			if dryRun {
+				g.stdout.Write([]byte("Would remove " + p + "\n"))
+				continue
+			}
+			g.progress("=> Removing " + p + "...")
+			err = os.RemoveAll(p)
+			if err != nil {
+				return err
+			}
+			err = removeEmptyParents(path.Dir(p), tops[p])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if !dryRun {
+		g.progress("=> Done!")
+	}
+	return nil
+}
+
+// repoRoots returns the import paths of the repositories under srcPath.
+func repoRoots(srcPath string) ([]string, error) {
+	var roots []string
+	err := filepath.Walk(srcPath, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == srcPath {
+				return nil
+			}
+			return err
+		}
+		if !fi.IsDir() || p == srcPath {
+			return nil
+		}
+		for _, d := range vcsDirs {
+			if exists, _ := pathExists(path.Join(p, d)); exists {
+				roots = append(roots, p[len(srcPath)+1:])
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	sort.Strings(roots)
+	return roots, err
+}
+
+// containsLocked reports whether a locked repository lives inside root.
+func containsLocked(root string, locked map[string]bool) bool {
+	for l := range locked {
+		if strings.HasPrefix(l, root+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// commandNames returns the names of the commands go install builds from the
+// main packages under dir.
+func commandNames(dir string) ([]string, error) {
+	var names []string
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			name := fi.Name()
+			if p != dir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+				return filepath.SkipDir
+			}
+			if isMainPackage(p) {
+				names = append(names, path.Base(p))
+			}
+		}
+		return nil
+	})
+	return names, err
+}
+
+func isMainPackage(dir string) bool {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, fi := range fis {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".go") || strings.HasSuffix(fi.Name(), "_test.go") {
+			continue
+		}
+		f, err := goparser.ParseFile(token.NewFileSet(), path.Join(dir, fi.Name()), nil, goparser.PackageClauseOnly)
+		if err == nil && f.Name.Name == "main" {
+			return true
+		}
+	}
+	return false
+}
+
+// removeEmptyParents removes dir and its parents up to, but not including,
+// top while they are empty.
+func removeEmptyParents(dir string, top string) error {
+	for dir != top && strings.HasPrefix(dir, top+"/") {
+		fis, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if len(fis) > 0 {
+			return nil
+		}
+		err = os.Remove(dir)
+		if err != nil {
+			return err
+		}
+		dir = path.Dir(dir)
+	}
+	return nil
+}
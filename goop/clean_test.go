@@ -0,0 +1,80 @@
+package goop_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/nitrous-io/goop/goop"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Clean()", func() {
+	var (
+		dir    string
+		vendor string
+		out    *bytes.Buffer
+		g      *goop.Goop
+	)
+
+	touch := func(p string, content string) {
+		Expect(os.MkdirAll(path.Dir(p), 0775)).To(Succeed())
+		Expect(ioutil.WriteFile(p, []byte(content), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		dir = tempDir()
+		vendor = path.Join(dir, ".vendor")
+		out = &bytes.Buffer{}
+		g = newTestGoop(dir, out)
+		g.SetVerbosity(goop.Quiet)
+
+		touch(path.Join(dir, "Goopfile.lock"), `{
+			"version": 2,
+			"dependencies": [
+				{"package": "github.com/nitrous-io/keep/sub", "rev": "aaa", "vcs": "git", "root": "github.com/nitrous-io/keep"}
+			]
+		}`)
+		touch(path.Join(vendor, "src/github.com/nitrous-io/keep/.git/HEAD"), "")
+		touch(path.Join(vendor, "src/github.com/nitrous-io/keep/cmd/tool/main.go"), "package main\n")
+		touch(path.Join(vendor, "pkg/linux_amd64/github.com/nitrous-io/keep/sub.a"), "")
+		touch(path.Join(vendor, "bin/tool"), "")
+
+		touch(path.Join(vendor, "src/github.com/gone/away/.git/HEAD"), "")
+		touch(path.Join(vendor, "src/github.com/gone/away/away.go"), "package away\n")
+		touch(path.Join(vendor, "src/github.com/gone/away/cmd/awayd/main.go"), "// awayd serves\npackage main\n")
+		touch(path.Join(vendor, "src/github.com/gone/away/cmd/tool/main.go"), "package main\n")
+		touch(path.Join(vendor, "pkg/linux_amd64/github.com/gone/away.a"), "")
+		touch(path.Join(vendor, "pkg/linux_amd64/github.com/gone/away/sub.a"), "")
+		touch(path.Join(vendor, "bin/awayd"), "")
+	})
+
+	exists := func(p string) bool {
+		_, err := os.Stat(path.Join(vendor, p))
+		return err == nil
+	}
+
+	It("removes repositories that are not in Goopfile.lock, with their packages and commands", func() {
+		Expect(g.Clean(false)).To(Succeed())
+		Expect(exists("src/github.com/gone")).To(BeFalse())
+		Expect(exists("pkg/linux_amd64/github.com/gone")).To(BeFalse())
+		Expect(exists("bin/awayd")).To(BeFalse())
+
+		Expect(exists("src/github.com/nitrous-io/keep/cmd/tool/main.go")).To(BeTrue())
+		Expect(exists("pkg/linux_amd64/github.com/nitrous-io/keep/sub.a")).To(BeTrue())
+		Expect(exists("bin/tool")).To(BeTrue())
+		Expect(exists("src")).To(BeTrue())
+	})
+
+	It("only prints what it would remove with dryRun", func() {
+		Expect(g.Clean(true)).To(Succeed())
+		Expect(out.String()).To(Equal("Would remove " + vendor + "/bin/awayd\n" +
+			"Would remove " + vendor + "/pkg/linux_amd64/github.com/gone/away\n" +
+			"Would remove " + vendor + "/pkg/linux_amd64/github.com/gone/away.a\n" +
+			"Would remove " + vendor + "/src/github.com/gone/away\n"))
+		Expect(exists("src/github.com/gone/away")).To(BeTrue())
+	})
+})
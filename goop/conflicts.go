@@ -0,0 +1,105 @@
+package goop
+
+import "strings"
+
+// Requirement records a request for a package at a given revision and URL.
+// Explicit requirements come from the Goopfile (or Goopfile.lock) being
+// installed; the rest are discovered while fetching sub-dependencies.
+type Requirement struct {
+	Pkg       string
+	Rev       string
+	URL       string
+	Requester string
+	Explicit  bool
+}
+
+func (r *Requirement) String() string {
+	s := r.Pkg
+	if r.Rev != "" {
+		s += " #" + r.Rev
+	}
+	if r.URL != "" {
+		s += " !" + r.URL
+	}
+	return s + " (required by " + r.Requester + ")"
+}
+
+func (r *Requirement) rank() int {
+	rank := 0
+	if r.Explicit {
+		rank += 2
+	}
+	if r.Rev != "" {
+		rank++
+	}
+	return rank
+}
+
+func (r *Requirement) conflictsWith(o *Requirement) bool {
+	return (r.Rev != "" && o.Rev != "" && r.Rev != o.Rev) || r.URL != o.URL
+}
+
+type ConflictError struct {
+	Root         string
+	Requirements []*Requirement
+}
+
+func (e *ConflictError) Error() string {
+	lines := []string{e.Root + " is required at conflicting revisions or URLs:"}
+	for _, r := range e.Requirements {
+		lines = append(lines, "  "+r.String())
+	}
+	explicit := false
+	for _, r := range e.Requirements {
+		explicit = explicit || r.Explicit
+	}
+	if explicit {
+		lines = append(lines, "Make these Goopfile entries agree on a single revision and URL.")
+	} else {
+		lines = append(lines, "Add an entry for "+e.Root+" to the Goopfile to choose one.")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Requirements groups requirements by repository root so that conflicting
+// requests for the same repository can be detected.
+type Requirements map[string][]*Requirement
+
+func NewRequirements() Requirements {
+	return Requirements{}
+}
+
+// Add records req for the repository root. It returns a *ConflictError if req
+// conflicts with an earlier requirement, unless exactly one of the two is
+// explicit, in which case the explicit requirement wins.
+func (rs Requirements) Add(root string, req *Requirement) error {
+	conflicts := rs.conflicting(root, req)
+	if len(conflicts) > 0 {
+		return &ConflictError{Root: root, Requirements: append(conflicts, req)}
+	}
+	rs[root] = append(rs[root], req)
+	return nil
+}
+
+// Resolved returns the requirement that decides the revision and URL of the
+// repository root: the first pinned explicit requirement if there is one,
+// otherwise the first pinned requirement, otherwise the first requirement.
+func (rs Requirements) Resolved(root string) *Requirement {
+	var resolved *Requirement
+	for _, r := range rs[root] {
+		if resolved == nil || r.rank() > resolved.rank() {
+			resolved = r
+		}
+	}
+	return resolved
+}
+
+func (rs Requirements) conflicting(root string, req *Requirement) []*Requirement {
+	var reqs []*Requirement
+	for _, r := range rs[root] {
+		if r.Explicit == req.Explicit && r.conflictsWith(req) {
+			reqs = append(reqs, r)
+		}
+	}
+	return reqs
+}
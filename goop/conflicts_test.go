@@ -0,0 +1,64 @@
+package goop_test
+
+import (
+	"github.com/nitrous-io/goop/goop"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("conflicts", func() {
+	var reqs goop.Requirements
+
+	BeforeEach(func() {
+		reqs = goop.NewRequirements()
+	})
+
+	Describe("Add()", func() {
+		It("accepts requirements that agree", func() {
+			Expect(reqs.Add("github.com/foo/bar", &goop.Requirement{Pkg: "github.com/foo/bar/a", Rev: "v1", Requester: "Goopfile", Explicit: true})).To(Succeed())
+			Expect(reqs.Add("github.com/foo/bar", &goop.Requirement{Pkg: "github.com/foo/bar/b", Rev: "v1", Requester: "Goopfile", Explicit: true})).To(Succeed())
+			Expect(reqs.Add("github.com/foo/bar", &goop.Requirement{Pkg: "github.com/foo/bar/c", Requester: "Goopfile", Explicit: true})).To(Succeed())
+		})
+
+		It("fails when explicit requirements ask for different revisions", func() {
+			Expect(reqs.Add("github.com/foo/bar", &goop.Requirement{Pkg: "github.com/foo/bar/a", Rev: "v1", Requester: "Goopfile", Explicit: true})).To(Succeed())
+			err := reqs.Add("github.com/foo/bar", &goop.Requirement{Pkg: "github.com/foo/bar/b", Rev: "v2", Requester: "Goopfile", Explicit: true})
+			Expect(err).To(BeAssignableToTypeOf(&goop.ConflictError{}))
+			Expect(err.(*goop.ConflictError).Requirements).To(HaveLen(2))
+			Expect(err.Error()).To(ContainSubstring("github.com/foo/bar/a #v1 (required by Goopfile)"))
+			Expect(err.Error()).To(ContainSubstring("github.com/foo/bar/b #v2 (required by Goopfile)"))
+		})
+
+		It("fails when explicit requirements ask for different URLs", func() {
+			Expect(reqs.Add("github.com/foo/bar", &goop.Requirement{Pkg: "github.com/foo/bar", Requester: "Goopfile", Explicit: true})).To(Succeed())
+			err := reqs.Add("github.com/foo/bar", &goop.Requirement{Pkg: "github.com/foo/bar/b", URL: "git@example.com:bar", Requester: "Goopfile", Explicit: true})
+			Expect(err).To(BeAssignableToTypeOf(&goop.ConflictError{}))
+		})
+
+		It("fails when sub-dependencies ask for different revisions", func() {
+			Expect(reqs.Add("github.com/foo/bar", &goop.Requirement{Pkg: "github.com/foo/bar", Rev: "v1", Requester: "github.com/a/a"})).To(Succeed())
+			err := reqs.Add("github.com/foo/bar", &goop.Requirement{Pkg: "github.com/foo/bar", Rev: "v2", Requester: "github.com/b/b"})
+			Expect(err).To(BeAssignableToTypeOf(&goop.ConflictError{}))
+			Expect(err.Error()).To(ContainSubstring("Add an entry for github.com/foo/bar to the Goopfile"))
+		})
+
+		It("lets an explicit requirement win over a sub-dependency", func() {
+			Expect(reqs.Add("github.com/foo/bar", &goop.Requirement{Pkg: "github.com/foo/bar", Rev: "v1", Requester: "Goopfile", Explicit: true})).To(Succeed())
+			Expect(reqs.Add("github.com/foo/bar", &goop.Requirement{Pkg: "github.com/foo/bar", Rev: "v2", Requester: "github.com/b/b"})).To(Succeed())
+			Expect(reqs.Resolved("github.com/foo/bar").Rev).To(Equal("v1"))
+		})
+	})
+
+	Describe("Resolved()", func() {
+		It("prefers pinned requirements", func() {
+			Expect(reqs.Add("github.com/foo/bar", &goop.Requirement{Pkg: "github.com/foo/bar/a", Requester: "Goopfile", Explicit: true})).To(Succeed())
+			Expect(reqs.Add("github.com/foo/bar", &goop.Requirement{Pkg: "github.com/foo/bar/b", Rev: "v1", Requester: "Goopfile", Explicit: true})).To(Succeed())
+			Expect(reqs.Resolved("github.com/foo/bar").Pkg).To(Equal("github.com/foo/bar/b"))
+		})
+
+		It("returns nil for an unknown root", func() {
+			Expect(reqs.Resolved("github.com/foo/bar")).To(BeNil())
+		})
+	})
+})
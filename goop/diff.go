@@ -0,0 +1,237 @@
+package goop
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/nitrous-io/goop/parser"
+)
+
+type UnknownLockError struct {
+	Spec string
+}
+
+func (e *UnknownLockError) Error() string {
+	return fmt.Sprintf("%s is neither a lock file nor a git revision", e.Spec)
+}
+
+// LockChange is a package added, removed or changed between two lock files.
+// Old is nil for added packages, and New is nil for removed ones.
+type LockChange struct {
+	Old *parser.Dependency
+	New *parser.Dependency
+}
+
+// DiffLocks returns the packages added, removed or changed in revision or URL
+// from oldDeps to newDeps, sorted by package.
+func DiffLocks(oldDeps []*parser.Dependency, newDeps []*parser.Dependency) []*LockChange {
+	changes := map[string]*LockChange{}
+	for _, dep := range oldDeps {
+		changes[dep.Pkg] = &LockChange{Old: dep}
+	}
+	for _, dep := range newDeps {
+		c := changes[dep.Pkg]
+		if c == nil {
+			changes[dep.Pkg] = &LockChange{New: dep}
+			continue
+		}
+		if c.Old.Rev == dep.Rev && c.Old.URL == dep.URL {
+			delete(changes, dep.Pkg)
+			continue
+		}
+		c.New = dep
+	}
+
+	var keys []string
+	for k := range changes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	sorted := make([]*LockChange, 0, len(keys))
+	for _, k := range keys {
+		sorted = append(sorted, changes[k])
+	}
+	return sorted
+}
+
+// Diff prints the packages added, removed and changed between two versions of
+// Goopfile.lock, along with the commits between the old and new revision of
+// each changed package. Each of oldSpec and newSpec is the path of a lock
+// file or a git revision of the project's Goopfile.lock; oldSpec defaults to
+// HEAD, and newSpec to the Goopfile.lock in the project directory.
+func (g *Goop) Diff(oldSpec string, newSpec string) error {
+	if oldSpec == "" {
+		oldSpec = "HEAD"
+	}
+	oldLock, err := g.readLockAt(oldSpec)
+	if err != nil {
+		return err
+	}
+	var newLock *parser.Lock
+	if newSpec == "" {
+		newLock, err = g.readLock()
+	} else {
+		newLock, err = g.readLockAt(newSpec)
+	}
+	if err != nil {
+		return err
+	}
+
+	changes := DiffLocks(oldLock.Deps, newLock.Deps)
+	if len(changes) == 0 {
+		g.progress("=> No changes")
+		return nil
+	}
+
+	for _, c := range changes {
+		switch {
+		case c.Old == nil:
+			g.stdout.Write([]byte("+ " + c.New.Pkg + " " + lockedAt(c.New) + "\n"))
+		case c.New == nil:
+			g.stdout.Write([]byte("- " + c.Old.Pkg + " " + lockedAt(c.Old) + "\n"))
+		default:
+			g.stdout.Write([]byte("~ " + c.New.Pkg + " " + lockedAt(c.Old) + " -> " + lockedAt(c.New) + "\n"))
+			log, err := g.changeLog(c.Old, c.New)
+			if err != nil {
+				return err
+			}
+			if log == nil {
+				g.stdout.Write([]byte("    (commit log not available)\n"))
+			}
+			for _, l := range log {
+				g.stdout.Write([]byte("    " + l + "\n"))
+			}
+		}
+	}
+	return nil
+}
+
+// readLockAt reads the lock file at spec, or the project's Goopfile.lock at
+// git revision spec if there is no such file. A revision without a
+// Goopfile.lock gives an empty lock.
+func (g *Goop) readLockAt(spec string) (*parser.Lock, error) {
+	f, err := os.Open(g.projectPath(spec))
+	if err == nil {
+		defer f.Close()
+		return parser.ParseLock(f)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	cmd := exec.Command("git", "rev-parse", "--verify", "-q", spec+"^{commit}")
+	cmd.Dir = g.dir
+	if cmd.Run() != nil {
+		return nil, &UnknownLockError{Spec: spec}
+	}
+	cmd = exec.Command("git", "show", spec+":./Goopfile.lock")
+	cmd.Dir = g.dir
+	b, err := cmd.Output()
+	if err != nil {
+		return parser.NewLock([]*parser.Dependency{}), nil
+	}
+	return parser.ParseLock(bytes.NewReader(b))
+}
+
+// changeLog returns the commits from the revision of from to that of to, one
+// per line prefixed with "+", and the commits only in the revision of from
+// prefixed with "-". The log is read from the cached mirror of the
+// repository, or from its checkout in the vendor directory; nil means neither
+// has both revisions.
+func (g *Goop) changeLog(from *parser.Dependency, to *parser.Dependency) ([]string, error) {
+	repo, err := g.lockedRepo(to)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	if g.cacheDir() != "" {
+		// mirrors are named after the URL the repository is fetched from
+		url := repo.Repo
+		if to.URL == "" {
+			if rewritten, ok := URLRewrites(g.config.Rewrites).Rewrite(repo.Root); ok {
+				url = rewritten
+			}
+		}
+		paths = append(paths, path.Join(g.cacheDir(), repo.VCS.Cmd, mirrorName(url)))
+	}
+	paths = append(paths, path.Join(g.vendorDir(), "src", repo.Root))
+
+	for _, p := range paths {
+		exists, err := pathExists(p)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+		log, err := commitLog(repo.VCS.Cmd, p, from.Rev, to.Rev)
+		if err == nil {
+			return log, nil
+		}
+	}
+	return nil, nil
+}
+
+// commitLog returns the changes from revision from to revision to in the
+// repository at path, in the form changeLog returns.
+func commitLog(vcsCmd string, path string, from string, to string) ([]string, error) {
+	switch vcsCmd {
+	case "git":
+		out, err := vcsOutput(path, "git", "log", "--left-right", "--format=%m %h %s", from+"..."+to, "--")
+		if err != nil {
+			return nil, err
+		}
+		log := []string{}
+		for _, l := range out {
+			if strings.HasPrefix(l, "<") {
+				log = append(log, "-"+l[1:])
+			} else {
+				log = append(log, "+"+l[1:])
+			}
+		}
+		return log, nil
+	case "hg":
+		tmpl := "{node|short} {desc|firstline}\n"
+		added, err := vcsOutput(path, "hg", "log", "-r", "only("+to+", "+from+")", "--template", "+ "+tmpl)
+		if err != nil {
+			return nil, err
+		}
+		removed, err := vcsOutput(path, "hg", "log", "-r", "only("+from+", "+to+")", "--template", "- "+tmpl)
+		if err != nil {
+			return nil, err
+		}
+		return append(added, removed...), nil
+	}
+	return nil, &UnsupportedVCSError{VCS: vcsCmd}
+}
+
+// vcsOutput runs a vcs command in path and returns its output lines.
+func vcsOutput(path string, name string, args ...string) ([]string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = path
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	s := strings.TrimRight(string(out), "\n")
+	if s == "" {
+		return []string{}, nil
+	}
+	return strings.Split(s, "\n"), nil
+}
+
+// lockedAt describes the revision and URL dep is locked at.
+func lockedAt(dep *parser.Dependency) string {
+	s := "#" + dep.Rev
+	if dep.URL != "" {
+		s += " !" + dep.URL
+	}
+	return s
+}
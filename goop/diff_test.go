@@ -0,0 +1,104 @@
+package goop_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/nitrous-io/goop/goop"
+	"github.com/nitrous-io/goop/parser"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DiffLocks()", func() {
+	It("returns added, removed and changed packages sorted by package", func() {
+		a1 := &parser.Dependency{Pkg: "github.com/nitrous-io/a", Rev: "1"}
+		a2 := &parser.Dependency{Pkg: "github.com/nitrous-io/a", Rev: "2"}
+		b := &parser.Dependency{Pkg: "github.com/nitrous-io/b", Rev: "1"}
+		c := &parser.Dependency{Pkg: "github.com/nitrous-io/c", Rev: "1"}
+		d1 := &parser.Dependency{Pkg: "github.com/nitrous-io/d", Rev: "1"}
+		d2 := &parser.Dependency{Pkg: "github.com/nitrous-io/d", Rev: "1", URL: "git@example.com:d.git"}
+		same := &parser.Dependency{Pkg: "github.com/nitrous-io/same", Rev: "1"}
+
+		Expect(goop.DiffLocks([]*parser.Dependency{same, d1, b, a1}, []*parser.Dependency{a2, c, d2, same})).To(Equal([]*goop.LockChange{
+			{Old: a1, New: a2},
+			{Old: b},
+			{New: c},
+			{Old: d1, New: d2},
+		}))
+	})
+})
+
+var _ = Describe("Diff()", func() {
+	var (
+		dir     string
+		repoDir string
+		revs    []string
+		out     *bytes.Buffer
+		g       *goop.Goop
+	)
+
+	writeLock := func(name string, deps string) {
+		Expect(ioutil.WriteFile(path.Join(dir, name), []byte(`{"version": 2, "dependencies": [`+deps+`]}`), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		dir = tempDir()
+		out = &bytes.Buffer{}
+		g = newTestGoop(dir, out)
+
+		repoDir = path.Join(dir, ".vendor/src/github.com/nitrous-io/a")
+		Expect(os.MkdirAll(repoDir, 0775)).To(Succeed())
+		gitIn(repoDir, "init", "-q")
+		revs = nil
+		for _, msg := range []string{"Initial import", "Fix a bug", "Add a feature"} {
+			gitIn(repoDir, "commit", "-q", "--allow-empty", "-m", msg)
+			revs = append(revs, gitIn(repoDir, "rev-parse", "HEAD"))
+		}
+
+		gitIn(dir, "init", "-q")
+		writeLock("Goopfile.lock", `
+			{"package": "github.com/nitrous-io/a", "rev": "`+revs[0]+`"},
+			{"package": "github.com/nitrous-io/b", "rev": "v1.0"}`)
+		gitIn(dir, "add", "Goopfile.lock")
+		gitIn(dir, "commit", "-q", "-m", "Lock dependencies")
+		writeLock("Goopfile.lock", `
+			{"package": "github.com/nitrous-io/a", "rev": "`+revs[2]+`"},
+			{"package": "github.com/nitrous-io/c", "rev": "v2.0"}`)
+	})
+
+	short := func(rev string) string {
+		return gitIn(repoDir, "rev-parse", "--short", rev)
+	}
+
+	It("compares HEAD with the working Goopfile.lock by default", func() {
+		Expect(g.Diff("", "")).To(Succeed())
+		Expect(out.String()).To(Equal("~ github.com/nitrous-io/a #" + revs[0] + " -> #" + revs[2] + "\n" +
+			"    + " + short(revs[2]) + " Add a feature\n" +
+			"    + " + short(revs[1]) + " Fix a bug\n" +
+			"- github.com/nitrous-io/b #v1.0\n" +
+			"+ github.com/nitrous-io/c #v2.0\n"))
+	})
+
+	It("compares lock files, and marks commits only in the old revision", func() {
+		writeLock("old.lock", `{"package": "github.com/nitrous-io/a", "rev": "`+revs[2]+`"}`)
+		writeLock("new.lock", `{"package": "github.com/nitrous-io/a", "rev": "`+revs[1]+`"}`)
+		Expect(g.Diff("old.lock", "new.lock")).To(Succeed())
+		Expect(out.String()).To(Equal("~ github.com/nitrous-io/a #" + revs[2] + " -> #" + revs[1] + "\n" +
+			"    - " + short(revs[2]) + " Add a feature\n"))
+	})
+
+	It("notes when the commit log is not available", func() {
+		writeLock("new.lock", `{"package": "github.com/nitrous-io/b", "rev": "v2.0"}`)
+		Expect(g.Diff("HEAD", "new.lock")).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("~ github.com/nitrous-io/b #v1.0 -> #v2.0\n    (commit log not available)\n"))
+	})
+
+	It("fails for arguments that are neither lock files nor revisions", func() {
+		err := g.Diff("nope", "")
+		Expect(err).To(BeAssignableToTypeOf(&goop.UnknownLockError{}))
+	})
+})
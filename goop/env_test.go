@@ -0,0 +1,49 @@
+package goop_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/nitrous-io/goop/goop"
+	"github.com/nitrous-io/goop/pkg/env"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("environment", func() {
+	var (
+		dir string
+		out *bytes.Buffer
+		g   *goop.Goop
+	)
+
+	BeforeEach(func() {
+		dir = tempDir()
+		out = &bytes.Buffer{}
+		g = newTestGoop(dir, out)
+	})
+
+	It("is the same for goop env and goop exec", func() {
+		Expect(g.PrintEnv("json", false)).To(Succeed())
+		printed := map[string]*string{}
+		Expect(json.Unmarshal(out.Bytes(), &printed)).To(Succeed())
+
+		out.Reset()
+		Expect(g.Exec("env")).To(Succeed())
+		execEnv := env.Env{}
+		for _, l := range strings.Split(out.String(), "\n") {
+			kv := strings.SplitN(l, "=", 2)
+			if len(kv) == 2 {
+				execEnv[kv[0]] = kv[1]
+			}
+		}
+
+		for _, k := range env.VendorVars {
+			Expect(printed[k]).NotTo(BeNil())
+			Expect(execEnv[k]).To(Equal(*printed[k]))
+		}
+		Expect(execEnv["GOBIN"]).To(Equal(dir + "/.vendor/bin"))
+	})
+})
@@ -0,0 +1,128 @@
+package goop_test
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/nitrous-io/goop/goop"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Exec()", func() {
+	var (
+		dir string
+		out *bytes.Buffer
+		g   *goop.Goop
+	)
+
+	BeforeEach(func() {
+		dir = tempDir()
+		out = &bytes.Buffer{}
+		g = newTestGoop(dir, out)
+	})
+
+	It("returns the exit code of a failed command", func() {
+		err := g.Exec("sh", "-c", "exit 3")
+		Expect(err).To(BeAssignableToTypeOf(&goop.ExitError{}))
+		Expect(err.(*goop.ExitError).Code).To(Equal(3))
+		Expect(err.(*goop.ExitError).ExitCode()).To(Equal(3))
+	})
+
+	It("returns the signal that terminated a command", func() {
+		err := g.Exec("sh", "-c", "kill -TERM $$")
+		Expect(err).To(BeAssignableToTypeOf(&goop.ExitError{}))
+		Expect(err.(*goop.ExitError).Signal).To(Equal(syscall.SIGTERM))
+		Expect(err.(*goop.ExitError).ExitCode()).To(Equal(128 + 15))
+	})
+
+	It("loads dotenv files relative to the project directory", func() {
+		Expect(ioutil.WriteFile(path.Join(dir, ".env"), []byte("GREETING=hello\nGOBIN=/nope\n"), 0644)).To(Succeed())
+		g.SetEnvFiles([]string{".env"})
+		Expect(g.Exec("sh", "-c", "echo $GREETING $GOBIN")).To(Succeed())
+		Expect(out.String()).To(Equal("hello " + dir + "/.vendor/bin\n"))
+	})
+
+	It("returns other errors as they are", func() {
+		err := g.Exec("goop-no-such-command")
+		Expect(err).NotTo(BeNil())
+		Expect(err).NotTo(BeAssignableToTypeOf(&goop.ExitError{}))
+	})
+
+	Context("when the project has a Goopfile", func() {
+		lock := []byte(`{"version": 2, "dependencies": []}`)
+
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(path.Join(dir, "Goopfile"), []byte(""), 0644)).To(Succeed())
+			Expect(os.MkdirAll(path.Join(dir, ".vendor"), 0775)).To(Succeed())
+		})
+
+		It("fails if there is no Goopfile.lock", func() {
+			Expect(g.Exec("true")).To(BeAssignableToTypeOf(&goop.StaleVendorError{}))
+		})
+
+		It("fails if dependencies have not been installed", func() {
+			Expect(ioutil.WriteFile(path.Join(dir, "Goopfile.lock"), lock, 0644)).To(Succeed())
+			Expect(g.Exec("true")).To(BeAssignableToTypeOf(&goop.StaleVendorError{}))
+		})
+
+		It("runs the command if Goopfile.lock has been installed", func() {
+			Expect(ioutil.WriteFile(path.Join(dir, "Goopfile.lock"), lock, 0644)).To(Succeed())
+			state := fmt.Sprintf(`{"lock_sha1": "%x", "revs": {}}`, sha1.Sum(lock))
+			Expect(ioutil.WriteFile(path.Join(dir, ".vendor", ".goop-state"), []byte(state), 0644)).To(Succeed())
+			Expect(g.Exec("true")).To(Succeed())
+
+			Expect(ioutil.WriteFile(path.Join(dir, "Goopfile.lock"), append(lock, '\n'), 0644)).To(Succeed())
+			Expect(g.Exec("true")).To(BeAssignableToTypeOf(&goop.StaleVendorError{}))
+		})
+
+		It("lists the packages that are not installed at their locked revision", func() {
+			state := `{"lock_sha1": "old", "revs": {"github.com/nitrous-io/a": "v1", "github.com/nitrous-io/b": "v1"}}`
+			Expect(ioutil.WriteFile(path.Join(dir, ".vendor", ".goop-state"), []byte(state), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(path.Join(dir, "Goopfile.lock"), []byte(`{"version": 2, "dependencies": [
+				{"package": "github.com/nitrous-io/a", "rev": "v1"},
+				{"package": "github.com/nitrous-io/b", "rev": "v2"},
+				{"package": "github.com/nitrous-io/c", "rev": "v1"}
+			]}`), 0644)).To(Succeed())
+
+			err := g.Exec("true")
+			Expect(err).To(BeAssignableToTypeOf(&goop.StaleVendorError{}))
+			Expect(err.(*goop.StaleVendorError).Pkgs).To(Equal([]string{"github.com/nitrous-io/b", "github.com/nitrous-io/c"}))
+			Expect(err.Error()).To(ContainSubstring("(out of date: github.com/nitrous-io/b, github.com/nitrous-io/c)"))
+		})
+	})
+
+	Context("when isolated", func() {
+		var gopath, projDir string
+
+		BeforeEach(func() {
+			gopath = os.Getenv("GOPATH")
+			projDir = path.Join(dir, "src", "example.com", "proj")
+			Expect(os.MkdirAll(projDir, 0775)).To(Succeed())
+			g = newTestGoop(projDir, out)
+			g.SetIsolated(true)
+		})
+
+		AfterEach(func() {
+			os.Setenv("GOPATH", gopath)
+		})
+
+		It("uses only the vendor directory and the project as GOPATH", func() {
+			os.Setenv("GOPATH", "/elsewhere:"+dir)
+			Expect(g.Exec("sh", "-c", `echo $GOPATH; readlink "${GOPATH##*:}/src/example.com/proj"`)).To(Succeed())
+			Expect(out.String()).To(Equal(projDir + "/.vendor:" + projDir + "/.vendor/self\n" + projDir + "\n"))
+		})
+
+		It("uses only the vendor directory if the project is not in GOPATH", func() {
+			os.Setenv("GOPATH", "/elsewhere")
+			Expect(g.Exec("sh", "-c", "echo $GOPATH")).To(Succeed())
+			Expect(out.String()).To(Equal(projDir + "/.vendor\n"))
+		})
+	})
+})
@@ -4,6 +4,12 @@ import (
 	"io"
 	"os/exec"
 	"regexp"
+	"strings"
+
+	"code.google.com/p/go.tools/go/vcs"
+
+	"github.com/nitrous-io/goop/parser"
+	"github.com/nitrous-io/goop/pkg/env"
 )
 
 var goGetDownloadRe = regexp.MustCompile(`(?m)^(\S+)\s+\(download\)$`)
@@ -36,20 +42,67 @@ func (d *DownloadRecorder) Downloads() []string {
 	return s
 }
 
-func (g *Goop) goGet(pkgpath string, gopath string) ([]string, error) {
+func (g *Goop) goGet(pkg string, pkgpath string, gopath string) ([]string, error) {
+	creds, err := g.credentials()
+	if err != nil {
+		return nil, err
+	}
+
 	cmd := exec.Command("go", "get", "-d", "-v", "./...")
-	env := g.patchedEnv(true)
-	env["GOPATH"] = gopath
+	// the temporary GOPATH stands in for the vendor directory
+	e := env.NewEnv().Vendor(gopath, true)
+	g.gitEnv(e, creds, "")
 	cmd.Dir = pkgpath
-	cmd.Env = env.Strings()
+	cmd.Env = e.Strings()
 	cmd.Stdin = g.stdin
-	cmd.Stdout = g.stdout
-	dlRec := NewDownloadRecorder(g.stderr)
+	stdout, stderr, done := g.childOutput(pkg)
+	cmd.Stdout = stdout
+	dlRec := NewDownloadRecorder(stderr)
 	cmd.Stderr = dlRec
-	err := cmd.Run()
+	err = done(cmd.Run())
 	if err != nil {
 		return nil, err
 	}
 
 	return dlRec.Downloads(), nil
 }
+
+// goListDeps returns the packages imported, directly or indirectly, by the
+// packages in pkgpath.
+func (g *Goop) goListDeps(pkg string, pkgpath string, gopath string) ([]string, error) {
+	cmd := exec.Command("go", "list", "-e", "-f", `{{join .Deps "\n"}}`, "./...")
+	cmd.Dir = pkgpath
+	cmd.Env = env.NewEnv().Vendor(gopath, true).Strings()
+	_, stderr, done := g.childOutput(pkg)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	err = done(err)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// AddImportParents records each direct dependency as a parent of the
+// transitive dependencies whose repository it imports from. imports maps
+// each direct dependency to the packages it imports, directly or indirectly,
+// and repos maps packages to their repository.
+func AddImportParents(locked map[string]*parser.Dependency, repos map[string]*vcs.RepoRoot, imports map[string][]string) {
+	for _, dep := range locked {
+		if _, ok := imports[dep.Pkg]; ok {
+			continue
+		}
+		root := repos[dep.Pkg].Root
+		for parent, pkgs := range imports {
+			if repos[parent].Root == root {
+				continue
+			}
+			for _, p := range pkgs {
+				if p == root || strings.HasPrefix(p, root+"/") {
+					dep.AddParent(parent)
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package goop_test
+
+import (
+	"code.google.com/p/go.tools/go/vcs"
+
+	"github.com/nitrous-io/goop/goop"
+	"github.com/nitrous-io/goop/parser"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AddImportParents()", func() {
+	It("records every direct dependency that imports from a transitive dependency", func() {
+		a := &parser.Dependency{Pkg: "github.com/nitrous-io/a"}
+		b := &parser.Dependency{Pkg: "github.com/nitrous-io/b/cmd/b"}
+		c := &parser.Dependency{Pkg: "github.com/nitrous-io/c"}
+		x := &parser.Dependency{Pkg: "github.com/nitrous-io/x", Parents: []string{"github.com/nitrous-io/a"}}
+		y := &parser.Dependency{Pkg: "github.com/nitrous-io/y", Parents: []string{"github.com/nitrous-io/b/cmd/b"}}
+		locked := map[string]*parser.Dependency{a.Pkg: a, b.Pkg: b, c.Pkg: c, x.Pkg: x, y.Pkg: y}
+		repos := map[string]*vcs.RepoRoot{
+			a.Pkg: {Root: "github.com/nitrous-io/a"},
+			b.Pkg: {Root: "github.com/nitrous-io/b"},
+			c.Pkg: {Root: "github.com/nitrous-io/c"},
+			x.Pkg: {Root: "github.com/nitrous-io/x"},
+			y.Pkg: {Root: "github.com/nitrous-io/y"},
+		}
+
+		goop.AddImportParents(locked, repos, map[string][]string{
+			a.Pkg: {"fmt", "github.com/nitrous-io/x"},
+			b.Pkg: {"github.com/nitrous-io/b/lib", "github.com/nitrous-io/x/sub", "github.com/nitrous-io/y"},
+			c.Pkg: {"github.com/nitrous-io/a", "github.com/nitrous-io/xy"},
+		})
+
+		Expect(x.Parents).To(ConsistOf("github.com/nitrous-io/a", "github.com/nitrous-io/b/cmd/b"))
+		Expect(y.Parents).To(ConsistOf("github.com/nitrous-io/b/cmd/b"))
+		Expect(a.Parents).To(BeEmpty())
+		Expect(b.Parents).To(BeEmpty())
+	})
+})
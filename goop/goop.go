@@ -1,21 +1,34 @@
 package goop
 
 import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path"
+	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 
 	"code.google.com/p/go.tools/go/vcs"
 
 	"github.com/nitrous-io/goop/colors"
 	"github.com/nitrous-io/goop/parser"
+	"github.com/nitrous-io/goop/pkg/config"
 	"github.com/nitrous-io/goop/pkg/env"
 )
 
+var mirrorNameRe = regexp.MustCompile(`[^A-Za-z0-9._/-]+`)
+
 type UnsupportedVCSError struct {
 	VCS string
 }
@@ -24,142 +37,414 @@ func (e *UnsupportedVCSError) Error() string {
 	return fmt.Sprintf("%s is not supported.", e.VCS)
 }
 
+// Verbosity controls how much progress output goop prints.
+type Verbosity int
+
+const (
+	Quiet Verbosity = iota
+	Normal
+	Verbose
+)
+
+// ExitError is returned by Exec when the command exits with a non-zero
+// status or is terminated by a signal.
+type ExitError struct {
+	Command string
+	Code    int
+	Signal  syscall.Signal
+}
+
+func (e *ExitError) Error() string {
+	if e.Signal != 0 {
+		return fmt.Sprintf("%s was terminated by signal %d (%s)", e.Command, int(e.Signal), e.Signal)
+	}
+	return fmt.Sprintf("%s exited with status %d", e.Command, e.Code)
+}
+
+// ExitCode returns the status goop should exit with: the command's, or
+// 128+signal if it was terminated by a signal, as shells do.
+func (e *ExitError) ExitCode() int {
+	if e.Signal != 0 {
+		return 128 + int(e.Signal)
+	}
+	return e.Code
+}
+
 type Goop struct {
-	dir    string
-	stdin  io.Reader
-	stdout io.Writer
-	stderr io.Writer
+	dir         string
+	config      *config.Config
+	stdin       io.Reader
+	stdout      *colors.Writer
+	stderr      *colors.Writer
+	creds       Credentials
+	verbosity   Verbosity
+	autoInstall bool
+	isolated    bool
+	envFiles    []string
 }
 
-func NewGoop(dir string, stdin io.Reader, stdout io.Writer, stderr io.Writer) *Goop {
-	return &Goop{dir: dir, stdin: stdin, stdout: stdout, stderr: stderr}
+func NewGoop(dir string, cfg *config.Config, stdin io.Reader, stdout *colors.Writer, stderr *colors.Writer) *Goop {
+	return &Goop{dir: dir, config: cfg, stdin: stdin, stdout: stdout, stderr: stderr, verbosity: Normal, autoInstall: cfg.AutoInstall, isolated: cfg.Isolated}
 }
 
-func (g *Goop) patchedEnv(replaceGopath bool) env.Env {
-	e := env.NewEnv()
+func (g *Goop) SetVerbosity(v Verbosity) {
+	g.verbosity = v
+}
 
-	binPath := path.Join(g.vendorDir(), "bin")
+// SetIsolated sets whether Exec hides the user's GOPATH from commands.
+func (g *Goop) SetIsolated(isolated bool) {
+	g.isolated = isolated
+}
+
+// patchedEnv returns the environment for commands run with the installed
+// dependencies. If isolated, GOPATH is only the vendor directory.
+func (g *Goop) patchedEnv(isolated bool) env.Env {
+	return env.NewEnv().Vendor(g.vendorDir(), isolated)
+}
+
+// SetEnvFiles sets the dotenv files Exec loads variables from. Relative
+// paths are relative to the project directory.
+func (g *Goop) SetEnvFiles(filenames []string) {
+	g.envFiles = filenames
+}
 
-	if replaceGopath {
-		e["GOPATH"] = g.vendorDir()
-	} else {
-		e.Prepend("GOPATH", g.vendorDir())
+// execEnv returns the environment Exec runs commands in: the environment
+// goop was run in, with variables from the dotenv files added and the
+// vendor directory set up. If isolated, GOPATH is the vendor directory,
+// followed by a GOPATH containing only the project if it lives in a GOPATH.
+func (g *Goop) execEnv() (env.Env, error) {
+	base := env.NewEnv()
+	filenames := make([]string, len(g.envFiles))
+	for i, f := range g.envFiles {
+		filenames[i] = g.projectPath(f)
+	}
+	err := base.LoadDotenv(filenames...)
+	if err != nil {
+		return nil, err
 	}
-	e["GOBIN"] = binPath
-	e.Prepend("PATH", binPath)
 
-	return e
+	e := base.Vendor(g.vendorDir(), g.isolated)
+	if !g.isolated {
+		return e, nil
+	}
+	selfPath, err := g.linkSelf(base.Restore()["GOPATH"])
+	if err != nil {
+		return nil, err
+	}
+	if selfPath != "" {
+		e.Append("GOPATH", selfPath)
+	}
+	return e, nil
 }
 
-func (g *Goop) PrintEnv() {
-	gopath := os.Getenv("GOPATH")
-	if gopath == "" {
-		g.stdout.Write([]byte(fmt.Sprintf("GOPATH=%s\n", g.vendorDir())))
-	} else {
-		g.stdout.Write([]byte(fmt.Sprintf("GOPATH=%s:%s\n", g.vendorDir(), gopath)))
+// linkSelf links the project into vendor/self under its import path, if the
+// project lives in one of the directories in gopath, and returns the path of
+// the GOPATH containing the link. It returns "" if the project is not in
+// gopath.
+func (g *Goop) linkSelf(gopath string) (string, error) {
+	importPath := ""
+	for _, p := range filepath.SplitList(gopath) {
+		src := path.Join(p, "src") + "/"
+		if p != "" && strings.HasPrefix(g.dir, src) {
+			importPath = g.dir[len(src):]
+			break
+		}
+	}
+	if importPath == "" {
+		return "", nil
 	}
-	g.stdout.Write([]byte(fmt.Sprintf("PATH=%s:%s\n", path.Join(g.vendorDir(), "bin"), os.Getenv("PATH"))))
+
+	selfPath := path.Join(g.vendorDir(), "self")
+	link := path.Join(selfPath, "src", importPath)
+	if target, err := os.Readlink(link); err == nil && target == g.dir {
+		return selfPath, nil
+	}
+	err := os.MkdirAll(path.Dir(link), 0775)
+	if err != nil {
+		return "", err
+	}
+	err = os.Remove(link)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	return selfPath, os.Symlink(g.dir, link)
 }
 
+// PrintEnv prints statements for shell that set up the environment to use
+// the installed dependencies, or with unset, that restore the environment to
+// what it was before. The previous values are kept in GOOP_OLD_* variables.
+func (g *Goop) PrintEnv(shell string, unset bool) error {
+	base := env.NewEnv().Restore()
+	set := env.Env{}
+	var unsetKeys []string
+
+	if unset {
+		for _, k := range env.VendorVars {
+			if v, ok := base[k]; ok {
+				set[k] = v
+			} else {
+				unsetKeys = append(unsetKeys, k)
+			}
+			unsetKeys = append(unsetKeys, env.OldPrefix+k)
+		}
+		unsetKeys = append(unsetKeys, env.ActiveVar)
+		return env.WriteShell(g.stdout, shell, set, unsetKeys)
+	}
+
+	// print exactly what goop exec would run commands with
+	patched, err := g.execEnv()
+	if err != nil {
+		return err
+	}
+	for _, k := range env.VendorVars {
+		set[k] = patched[k]
+		if v, ok := base[k]; ok {
+			set[env.OldPrefix+k] = v
+		} else {
+			unsetKeys = append(unsetKeys, env.OldPrefix+k)
+		}
+	}
+	set[env.ActiveVar] = g.vendorDir()
+	return env.WriteShell(g.stdout, shell, set, unsetKeys)
+}
+
+// Exec runs the named command with the installed dependencies, forwarding
+// SIGINT, SIGTERM and SIGHUP to it. If the command fails, the error is an
+// *ExitError. If the vendor directory does not match Goopfile.lock, the
+// error is a *StaleVendorError unless auto-install is on.
 func (g *Goop) Exec(name string, args ...string) error {
 	vname := path.Join(g.vendorDir(), "bin", name)
 	_, err := os.Stat(vname)
 	if err == nil {
 		name = vname
 	}
+	e, err := g.execEnv()
+	if err != nil {
+		return err
+	}
+	return g.execWith(e, name, args...)
+}
+
+// Shell starts $SHELL with the installed dependencies, and env.ShellVar set
+// to the project directory so that prompts can show it. The environment of
+// the calling shell is left untouched.
+func (g *Goop) Shell() error {
+	if active := os.Getenv(env.ShellVar); active != "" {
+		return errors.New("already in a goop shell for " + active + "; exit it first")
+	}
+	if active := os.Getenv(env.ActiveVar); active != "" {
+		return errors.New("already using the dependencies in " + active + ` from goop env; run eval "$(goop env --unset)" first`)
+	}
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+
+	e, err := g.execEnv()
+	if err != nil {
+		return err
+	}
+	e[env.ShellVar] = g.dir
+	g.progress("=> Starting " + shell + " with the dependencies in " + g.vendorDir() + "; exit to return")
+	return g.execWith(e, shell)
+}
+
+// execWith does the work of Exec, running the named command in e.
+func (g *Goop) execWith(e env.Env, name string, args ...string) error {
+	err := g.checkInstalled()
+	if err != nil {
+		return err
+	}
+
+	if g.verbosity == Verbose {
+		for _, l := range e.Diff(env.NewEnv()) {
+			g.stderr.Write([]byte("[env] " + l + "\n"))
+		}
+	}
+
 	cmd := exec.Command(name, args...)
-	cmd.Env = g.patchedEnv(false).Strings()
+	cmd.Env = e.Strings()
 	cmd.Stdin = g.stdin
-	cmd.Stdout = g.stdout
-	cmd.Stderr = g.stderr
-	return cmd.Run()
+	cmd.Stdout = g.stdout.Raw()
+	cmd.Stderr = g.stderr.Raw()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				cmd.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return exitError(path.Base(name), cmd.Wait())
 }
 
 func (g *Goop) Install() error {
-	writeLockFile := false
 	f, err := os.Open(path.Join(g.dir, "Goopfile.lock"))
-	if err == nil {
-		g.stdout.Write([]byte(colors.OK + "Using Goopfile.lock..." + colors.Reset + "\n"))
-	} else {
-		f, err = os.Open(path.Join(g.dir, "Goopfile"))
-		if err != nil {
-			return err
+	if err != nil {
+		return g.Update()
+	}
+	defer f.Close()
+
+	lock, err := parser.ParseLock(f)
+	if err != nil {
+		return err
+	}
+	g.progress("Using Goopfile.lock...")
+
+	if lock.GoopfileHash != "" {
+		b, err := ioutil.ReadFile(path.Join(g.dir, "Goopfile"))
+		if err == nil && goopfileHash(b) != lock.GoopfileHash {
+			g.stderr.Warn("Warning: Goopfile has changed since Goopfile.lock was written; run \"goop update\" to apply the changes.")
 		}
-		writeLockFile = true
 	}
-	return g.parseAndInstall(f, writeLockFile)
+
+	// older lock files do not record which packages introduced each
+	// sub-dependency, so they are left alone until the next update
+	if lock.Version < parser.LockVersion {
+		g.progress("Goopfile.lock is in format version " + strconv.Itoa(lock.Version) + ", which does not record where sub-dependencies came from; run \"goop update\" to upgrade it.")
+	}
+
+	lockedDeps, err := g.installDeps(lock.Deps)
+	if err != nil {
+		return err
+	}
+
+	err = g.writeState(lockedDeps)
+	if err != nil {
+		return err
+	}
+
+	g.progress("=> Done!")
+	return nil
 }
 
 func (g *Goop) Update() error {
-	f, err := os.Open(path.Join(g.dir, "Goopfile"))
+	b, err := ioutil.ReadFile(path.Join(g.dir, "Goopfile"))
+	if err != nil {
+		return err
+	}
+	deps, err := parser.Parse(bytes.NewReader(b))
 	if err != nil {
 		return err
 	}
-	return g.parseAndInstall(f, true)
-}
 
-func (g *Goop) parseAndInstall(goopfile *os.File, writeLockFile bool) error {
-	defer goopfile.Close()
+	lockedDeps, err := g.installDeps(deps)
+	if err != nil {
+		return err
+	}
 
-	deps, err := parser.Parse(goopfile)
+	lock := parser.NewLock(lockedDeps)
+	lock.GoopfileHash = goopfileHash(b)
+	err = g.writeLock(lock)
+	if err != nil {
+		return err
+	}
+	err = g.writeState(lockedDeps)
 	if err != nil {
 		return err
 	}
 
+	g.progress("=> Done!")
+	return nil
+}
+
+// installDeps fetches and installs deps along with their sub-dependencies,
+// and returns every installed dependency sorted by package.
+func (g *Goop) installDeps(deps []*parser.Dependency) ([]*parser.Dependency, error) {
 	srcPath := path.Join(g.vendorDir(), "src")
 	tmpGoPath := path.Join(g.vendorDir(), "tmp")
 	tmpSrcPath := path.Join(tmpGoPath, "src")
 
+	err := g.clearState()
+	if err != nil {
+		return nil, err
+	}
 	err = os.RemoveAll(tmpGoPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	err = os.MkdirAll(tmpSrcPath, 0775)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	repos := map[string]*vcs.RepoRoot{}
 	lockedDeps := map[string]*parser.Dependency{}
+	reqs := NewRequirements()
 
+	// resolve every repo first, so that entries sharing a repository can be
+	// checked for conflicts before anything is checked out
 	for _, dep := range deps {
-		if dep.URL == "" {
-			g.stdout.Write([]byte(colors.OK + "=> Fetching " + dep.Pkg + "..." + colors.Reset + "\n"))
-		} else {
-			g.stdout.Write([]byte(colors.OK + "=> Fetching " + dep.Pkg + " from " + dep.URL + "..." + colors.Reset + "\n"))
+		repo, err := g.repoForDep(dep)
+		if err != nil {
+			return nil, err
 		}
+		repos[dep.Pkg] = repo
 
-		repo, err := repoForDep(dep)
+		requester := "Goopfile"
+		if !dep.Direct() {
+			requester = strings.Join(dep.Parents, ", ")
+		}
+		err = reqs.Add(repo.Root, &Requirement{Pkg: dep.Pkg, Rev: dep.Rev, URL: dep.URL, Requester: requester, Explicit: true})
 		if err != nil {
-			return err
+			return nil, err
+		}
+	}
+
+	for _, dep := range deps {
+		repo := repos[dep.Pkg]
+
+		// entries without a revision follow a pinned entry in the same repo
+		if dep.Rev == "" {
+			dep.Rev = reqs.Resolved(repo.Root).Rev
+		}
+
+		if dep.URL == "" {
+			g.progress("=> Fetching " + dep.Pkg + "...")
+		} else {
+			g.progress("=> Fetching " + dep.Pkg + " from " + dep.URL + "...")
 		}
-		repos[dep.Pkg] = repo
 
 		pkgPath := path.Join(srcPath, repo.Root)
 		tmpPkgPath := path.Join(tmpSrcPath, repo.Root)
 
 		err = os.MkdirAll(path.Join(tmpPkgPath, ".."), 0775)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		noclone := false
 
 		exists, err := pathExists(pkgPath)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		tmpExists, err := pathExists(tmpPkgPath)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if exists {
 			// if package already exists, just symlink package dir and skip cloning
-			g.stderr.Write([]byte(colors.Warn + "Warning: " + pkgPath + " already exists; skipping!" + colors.Reset + "\n"))
+			g.stderr.Warn("Warning: " + pkgPath + " already exists; skipping!")
 			if !tmpExists {
 				err = os.Symlink(pkgPath, tmpPkgPath)
 				if err != nil {
-					return err
+					return nil, err
 				}
 			}
 			noclone = true
@@ -169,66 +454,98 @@ func (g *Goop) parseAndInstall(goopfile *os.File, writeLockFile bool) error {
 
 		if !noclone {
 			// clone repo
-			err = g.clone(repo.VCS.Cmd, repo.Repo, tmpPkgPath)
+			err = g.clone(dep.Pkg, repo.VCS.Cmd, repo.Repo, tmpPkgPath)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 
 		// if rev is not given, record current rev in path
 		if dep.Rev == "" {
-			rev, err := g.currentRev(repo.VCS.Cmd, tmpPkgPath)
+			rev, err := g.currentRev(dep.Pkg, repo.VCS.Cmd, tmpPkgPath)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			dep.Rev = rev
 		}
 		lockedDeps[dep.Pkg] = dep
 
 		// checkout specified rev
-		err = g.checkout(repo.VCS.Cmd, tmpPkgPath, dep.Rev)
+		err = g.checkout(dep.Pkg, repo.VCS.Cmd, repo.Repo, tmpPkgPath, dep.Rev)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
+	// honor the pins in each dependency's own Goopfile.lock (or Goopfile),
+	// so that go get does not fetch the latest revisions of its
+	// sub-dependencies; the top-level Goopfile wins on conflicts
+	overridden, err := ResolvePins(&pinFetcher{g: g, tmpSrcPath: tmpSrcPath}, deps, reqs, repos, lockedDeps)
+	if err != nil {
+		return nil, err
+	}
+	if len(overridden) > 0 {
+		g.stderr.Warn("Warning: Goopfile overrides pins from dependencies:\n  " + strings.Join(overridden, "\n  "))
+	}
+
 	for _, dep := range deps {
-		g.stdout.Write([]byte(colors.OK + "=> Fetching dependencies for " + dep.Pkg + "..." + colors.Reset + "\n"))
+		g.progress("=> Fetching dependencies for " + dep.Pkg + "...")
 
 		repo := repos[dep.Pkg]
 		tmpPkgPath := path.Join(tmpSrcPath, repo.Root)
 
 		// fetch sub-dependencies
-		subdeps, err := g.goGet(tmpPkgPath, tmpGoPath)
+		subdeps, err := g.goGet(dep.Pkg, tmpPkgPath, tmpGoPath)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		for _, subdep := range subdeps {
-			subdepRepo, err := vcs.RepoRootForImportPath(subdep, true)
+			subdepRepo, err := g.repoForDep(&parser.Dependency{Pkg: subdep})
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			subdepPkgPath := path.Join(tmpSrcPath, subdepRepo.Root)
 
-			rev, err := g.currentRev(subdepRepo.VCS.Cmd, subdepPkgPath)
+			rev, err := g.currentRev(subdep, subdepRepo.VCS.Cmd, subdepPkgPath)
 			if err != nil {
-				return err
+				return nil, err
 			}
 
-			err = g.checkout(subdepRepo.VCS.Cmd, subdepPkgPath, rev)
+			err = reqs.Add(subdepRepo.Root, &Requirement{Pkg: subdep, Rev: rev, Requester: dep.Pkg})
 			if err != nil {
-				return err
+				return nil, err
+			}
+
+			err = g.checkout(subdep, subdepRepo.VCS.Cmd, subdepRepo.Repo, subdepPkgPath, rev)
+			if err != nil {
+				return nil, err
 			}
 
 			repos[subdep] = subdepRepo
-			lockedDeps[subdep] = &parser.Dependency{Pkg: subdep, Rev: rev}
+			if lockedDeps[subdep] == nil {
+				lockedDeps[subdep] = &parser.Dependency{Pkg: subdep}
+			}
+			lockedDeps[subdep].Rev = rev
+			lockedDeps[subdep].AddParent(dep.Pkg)
+		}
+	}
+
+	// go get only reports the first dependency that downloads a repository,
+	// so work out every dependency that imports from it
+	imports := map[string][]string{}
+	for _, dep := range deps {
+		repo := repos[dep.Pkg]
+		imports[dep.Pkg], err = g.goListDeps(dep.Pkg, path.Join(tmpSrcPath, repo.Root), tmpGoPath)
+		if err != nil {
+			return nil, err
 		}
 	}
+	AddImportParents(lockedDeps, repos, imports)
 
 	for _, dep := range lockedDeps {
-		g.stdout.Write([]byte(colors.OK + "=> Installing " + dep.Pkg + "..." + colors.Reset + "\n"))
+		g.progress("=> Installing " + dep.Pkg + "...")
 
 		repo := repos[dep.Pkg]
 		pkgPath := path.Join(srcPath, repo.Root)
@@ -236,19 +553,19 @@ func (g *Goop) parseAndInstall(goopfile *os.File, writeLockFile bool) error {
 
 		err = os.MkdirAll(path.Join(pkgPath, ".."), 0775)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		lfi, err := os.Lstat(tmpPkgPath)
 		if err != nil && !os.IsNotExist(err) {
-			return err
+			return nil, err
 		}
 		if err == nil {
 			if lfi.Mode()&os.ModeSymlink == 0 {
 				// move package to vendor path
 				err = os.RemoveAll(pkgPath)
 				if err != nil {
-					return err
+					return nil, err
 				}
 				err = os.Rename(tmpPkgPath, pkgPath)
 			} else {
@@ -256,131 +573,374 @@ func (g *Goop) parseAndInstall(goopfile *os.File, writeLockFile bool) error {
 				err = os.Remove(tmpPkgPath)
 			}
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
 
+	// install, running up to config.Parallelism go installs at a time
+	sem := make(chan struct{}, g.config.Parallelism)
+	var wg sync.WaitGroup
 	for _, dep := range lockedDeps {
-		// install
 		repo := repos[dep.Pkg]
 		pkgPath := path.Join(srcPath, repo.Root)
-		cmd := g.command(pkgPath, "go", "install", "-x", dep.Pkg)
+		args := []string{"install", dep.Pkg}
+		if g.verbosity == Verbose {
+			args = []string{"install", "-x", dep.Pkg}
+		}
+		cmd := g.command(pkgPath, "go", args...)
 		cmd.Env = g.patchedEnv(true).Strings()
-		cmd.Run()
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(pkg string) {
+			defer wg.Done()
+			g.run(pkg, cmd)
+			<-sem
+		}(dep.Pkg)
 	}
+	wg.Wait()
 
 	err = os.RemoveAll(tmpGoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// in order to minimize diffs in the lock file, we return lockedDeps
+	// sorted by package
+	var keys []string
+	for k := range lockedDeps {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	sorted := make([]*parser.Dependency, 0, len(keys))
+	for _, k := range keys {
+		dep := lockedDeps[k]
+		repo := repos[dep.Pkg]
+		dep.VCS = repo.VCS.Cmd
+		dep.Root = repo.Root
+		sort.Strings(dep.Parents)
+		sorted = append(sorted, dep)
+	}
+
+	return sorted, nil
+}
+
+func (g *Goop) writeLock(lock *parser.Lock) error {
+	lf, err := os.Create(path.Join(g.dir, "Goopfile.lock"))
 	if err != nil {
 		return err
 	}
+	defer lf.Close()
+	return lock.Write(lf)
+}
 
-	// in order to minimize diffs, we sort lockedDeps first and write the
-	// sorted results
-	if writeLockFile {
-		lf, err := os.Create(path.Join(g.dir, "Goopfile.lock"))
-		defer lf.Close()
+func (g *Goop) PrintGraph(format string) error {
+	graph, err := g.readGraph()
+	if err != nil {
+		return err
+	}
+	return graph.Write(g.stdout, format)
+}
 
-		var keys []string
-		for k := range lockedDeps {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
+func (g *Goop) Why(pkg string) error {
+	graph, err := g.readGraph()
+	if err != nil {
+		return err
+	}
+	return graph.WriteWhy(g.stdout, pkg)
+}
 
-		for _, k := range keys {
-			dep := lockedDeps[k]
-			_, err = lf.WriteString(dep.String() + "\n")
-			if err != nil {
-				return err
-			}
-		}
+func (g *Goop) readGraph() (*Graph, error) {
+	lock, err := g.readLock()
+	if err != nil {
+		return nil, err
 	}
+	return NewGraphFromDeps(lock.Deps), nil
+}
 
-	g.stdout.Write([]byte(colors.OK + "=> Done!" + colors.Reset + "\n"))
+// readLock reads the project's Goopfile.lock.
+func (g *Goop) readLock() (*parser.Lock, error) {
+	f, err := os.Open(path.Join(g.dir, "Goopfile.lock"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New("Goopfile.lock not found; run \"goop install\" first")
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return parser.ParseLock(f)
+}
 
-	return nil
+func (g *Goop) PrintConfig() error {
+	return g.config.Write(g.stdout)
 }
 
 func (g *Goop) vendorDir() string {
-	return path.Join(g.dir, ".vendor")
+	return g.projectPath(g.config.VendorDir)
+}
+
+func (g *Goop) cacheDir() string {
+	if g.config.CacheDir == "" {
+		return ""
+	}
+	return g.projectPath(g.config.CacheDir)
+}
+
+// projectPath resolves p relative to the project directory.
+func (g *Goop) projectPath(p string) string {
+	if path.IsAbs(p) {
+		return p
+	}
+	return path.Join(g.dir, p)
 }
 
-func (g *Goop) currentRev(vcsCmd string, path string) (string, error) {
+func (g *Goop) currentRev(pkg string, vcsCmd string, path string) (string, error) {
+	var cmd *exec.Cmd
+	switch vcsCmd {
+	case "git":
+		cmd = exec.Command("git", "rev-parse", "--verify", "HEAD")
+	case "hg":
+		cmd = exec.Command("hg", "log", "-r", ".", "--template", "{node}")
+	default:
+		return "", &UnsupportedVCSError{VCS: vcsCmd}
+	}
+	cmd.Dir = path
+	_, stderr, done := g.childOutput(pkg)
+	cmd.Stderr = stderr
+	rev, err := cmd.Output()
+	err = done(err)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(rev)), nil
+}
+
+func (g *Goop) clone(pkg string, vcsCmd string, url string, clonePath string) error {
+	if g.cacheDir() == "" {
+		return g.remoteCommand(pkg, "", vcsCmd, url, "clone", url, clonePath)
+	}
+
+	// clone from a mirror in the cache, then point the clone back at url so
+	// that later fetches go to the real repository
+	mirrorPath, err := g.updateMirror(pkg, vcsCmd, url)
+	if err != nil {
+		return err
+	}
 	switch vcsCmd {
 	case "git":
-		cmd := exec.Command("git", "rev-parse", "--verify", "HEAD")
-		cmd.Dir = path
-		cmd.Stderr = g.stderr
-		rev, err := cmd.Output()
+		err = g.run(pkg, g.command("", "git", "clone", mirrorPath, clonePath))
 		if err != nil {
-			return "", err
-		} else {
-			return strings.TrimSpace(string(rev)), err
+			return err
 		}
+		return g.run(pkg, g.command(clonePath, "git", "remote", "set-url", "origin", url))
 	case "hg":
-		cmd := exec.Command("hg", "log", "-r", ".", "--template", "{node}")
-		cmd.Dir = path
-		cmd.Stderr = g.stderr
-		rev, err := cmd.Output()
+		err = g.run(pkg, g.command("", "hg", "clone", mirrorPath, clonePath))
 		if err != nil {
-			return "", err
-		} else {
-			return strings.TrimSpace(string(rev)), err
+			return err
 		}
+		return ioutil.WriteFile(path.Join(clonePath, ".hg", "hgrc"), []byte("[paths]\ndefault = "+url+"\n"), 0644)
 	}
-	return "", &UnsupportedVCSError{VCS: vcsCmd}
+	return &UnsupportedVCSError{VCS: vcsCmd}
 }
 
-func (g *Goop) clone(vcsCmd string, url string, clonePath string) error {
+// updateMirror creates or updates the cached mirror of url, and returns its
+// path.
+func (g *Goop) updateMirror(pkg string, vcsCmd string, url string) (string, error) {
+	mirrorPath := path.Join(g.cacheDir(), vcsCmd, mirrorName(url))
+	exists, err := pathExists(mirrorPath)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case vcsCmd == "git" && exists:
+		err = g.remoteCommand(pkg, mirrorPath, vcsCmd, url, "fetch", "--prune")
+	case vcsCmd == "git":
+		err = g.remoteCommand(pkg, "", vcsCmd, url, "clone", "--mirror", url, mirrorPath)
+	case vcsCmd == "hg" && exists:
+		err = g.remoteCommand(pkg, mirrorPath, vcsCmd, url, "pull")
+	case vcsCmd == "hg":
+		err = g.remoteCommand(pkg, "", vcsCmd, url, "clone", "-U", url, mirrorPath)
+	default:
+		err = &UnsupportedVCSError{VCS: vcsCmd}
+	}
+	if err != nil {
+		return "", err
+	}
+	return mirrorPath, nil
+}
+
+func (g *Goop) checkout(pkg string, vcsCmd string, url string, path string, tag string) error {
+	if g.verbosity == Verbose {
+		g.stdout.Write([]byte("[" + pkg + "] Checking out \"" + tag + "\"\n"))
+	}
 	switch vcsCmd {
 	case "git":
-		return g.command("", "git", "clone", url, clonePath).Run()
+		err := g.remoteCommand(pkg, path, vcsCmd, url, "fetch")
+		if err != nil {
+			return err
+		}
+		return g.run(pkg, g.command(path, "git", "checkout", tag))
 	case "hg":
-		return g.command("", "hg", "clone", url, clonePath).Run()
+		err := g.remoteCommand(pkg, path, vcsCmd, url, "pull")
+		if err != nil {
+			return err
+		}
+		return g.run(pkg, g.command(path, "hg", "update", tag))
 	}
 	return &UnsupportedVCSError{VCS: vcsCmd}
 }
 
-func (g *Goop) checkout(vcsCmd string, path string, tag string) error {
-	g.stdout.Write([]byte("Checking out \"" + tag + "\"\n"))
+// remoteCommand runs a vcs command that talks to the repository at url,
+// with any configured credentials for its host applied. Authentication and
+// missing repository failures are returned as *AuthError and
+// *RepoNotFoundError.
+func (g *Goop) remoteCommand(pkg string, path string, vcsCmd string, url string, args ...string) error {
+	creds, err := g.credentials()
+	if err != nil {
+		return err
+	}
+
+	var cmd *exec.Cmd
 	switch vcsCmd {
 	case "git":
-		err := g.command(path, "git", "fetch").Run()
+		// url has already been rewritten, or was given explicitly, so only
+		// credentials apply
+		cmd = g.command(path, "git", args...)
+		e := env.NewEnv()
+		creds.GitEnv(e, url)
+		cmd.Env = e.Strings()
+	case "hg":
+		e := env.NewEnv()
+		hgrc, err := creds.HgEnv(e)
 		if err != nil {
 			return err
 		}
-		return g.quietCommand(path, "git", "checkout", tag).Run()
-	case "hg":
-		err := g.command(path, "hg", "pull").Run()
+		if hgrc != "" {
+			defer os.Remove(hgrc)
+		}
+		cmd = g.command(path, "hg", append(creds.HgArgs(url), args...)...)
+		cmd.Env = e.Strings()
+	default:
+		return &UnsupportedVCSError{VCS: vcsCmd}
+	}
+
+	stdout, stderr, done := g.childOutput(pkg)
+	output := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = io.MultiWriter(stderr, output)
+	err = done(cmd.Run())
+	return ClassifyVCSError(url, output.String(), err)
+}
+
+// gitEnv adds credentials and URL rewrites to e for git commands, such as
+// those run by go get, that fetch repositories goop has not resolved itself.
+func (g *Goop) gitEnv(e env.Env, creds Credentials, url string) env.Env {
+	creds.GitEnv(e, url)
+	URLRewrites(g.config.Rewrites).GitEnv(e)
+	return e
+}
+
+func (g *Goop) credentials() (Credentials, error) {
+	if g.creds == nil {
+		creds, err := LoadCredentials()
 		if err != nil {
-			return err
+			return nil, err
 		}
-		return g.quietCommand(path, "hg", "update", tag).Run()
+		g.creds = creds
+	}
+	return g.creds, nil
+}
+
+// progress prints a progress message unless goop is quiet.
+func (g *Goop) progress(msg string) {
+	if g.verbosity > Quiet {
+		g.stdout.OK(msg)
 	}
-	return &UnsupportedVCSError{VCS: vcsCmd}
 }
 
 func (g *Goop) command(path string, name string, args ...string) *exec.Cmd {
 	cmd := exec.Command(name, args...)
 	cmd.Dir = path
 	cmd.Stdin = g.stdin
-	cmd.Stdout = g.stdout
-	cmd.Stderr = g.stderr
+	cmd.Stdout = g.stdout.Raw()
+	cmd.Stderr = g.stderr.Raw()
 	return cmd
 }
 
-func (g *Goop) quietCommand(path string, name string, args ...string) *exec.Cmd {
-	cmd := g.command(path, name, args...)
-	cmd.Stdout = nil
-	cmd.Stderr = nil
-	return cmd
-}
-
-func repoForDep(dep *parser.Dependency) (*vcs.RepoRoot, error) {
+// repoForDep resolves the repository for dep. Unless dep overrides the URL,
+// the configured rewrite rules decide where the repository is fetched from;
+// the import path itself is left untouched.
+func (g *Goop) repoForDep(dep *parser.Dependency) (*vcs.RepoRoot, error) {
 	if dep.URL != "" {
 		return RepoRootForImportPathWithURLOverride(dep.Pkg, dep.URL)
 	}
-	return vcs.RepoRootForImportPath(dep.Pkg, true)
+	repo, err := vcs.RepoRootForImportPath(dep.Pkg, true)
+	if err != nil {
+		return nil, err
+	}
+	if url, ok := URLRewrites(g.config.Rewrites).Rewrite(repo.Root); ok {
+		repo.Repo = url
+	}
+	return repo, nil
+}
+
+// lockedRepo returns the repository of a dependency read from Goopfile.lock,
+// using the version control system and root recorded there, so that no
+// network lookup is needed. Repo is the URL given in the Goopfile, or the
+// canonical URL for well-known hosts, ignoring rewrite rules; for other hosts
+// it is the repository root. Lock files without recorded roots are resolved
+// as on install.
+func (g *Goop) lockedRepo(dep *parser.Dependency) (*vcs.RepoRoot, error) {
+	if dep.Root == "" || dep.VCS == "" {
+		if dep.URL != "" {
+			return RepoRootForImportPathWithURLOverride(dep.Pkg, dep.URL)
+		}
+		return vcs.RepoRootForImportPath(dep.Pkg, true)
+	}
+
+	repo := &vcs.RepoRoot{VCS: vcs.ByCmd(dep.VCS), Root: dep.Root, Repo: dep.URL}
+	if repo.VCS == nil {
+		return nil, &UnsupportedVCSError{VCS: dep.VCS}
+	}
+	if repo.Repo == "" {
+		repo.Repo = dep.Root
+		if static, err := vcs.RepoRootForImportPathStatic(dep.Root, "ignore"); err == nil {
+			repo.Repo = static.Repo
+		}
+	}
+	return repo, nil
+}
+
+// exitError converts the error from running a command to an *ExitError if
+// the command ran but failed.
+func exitError(name string, err error) error {
+	eerr, ok := err.(*exec.ExitError)
+	if !ok {
+		return err
+	}
+	status, ok := eerr.Sys().(syscall.WaitStatus)
+	if !ok {
+		return err
+	}
+	if status.Signaled() {
+		return &ExitError{Command: name, Code: -1, Signal: status.Signal()}
+	}
+	return &ExitError{Command: name, Code: status.ExitStatus()}
+}
+
+// mirrorName turns a repository URL into a relative path for its mirror.
+func mirrorName(url string) string {
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+	}
+	return path.Clean("/" + mirrorNameRe.ReplaceAllString(url, "_"))[1:]
+}
+
+func contentHash(b []byte) string {
+	return fmt.Sprintf("%x", sha1.Sum(b))
 }
 
 // pathExists returns:
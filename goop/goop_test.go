@@ -1,8 +1,17 @@
 package goop_test
 
 import (
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
 	"testing"
 
+	"github.com/nitrous-io/goop/colors"
+	"github.com/nitrous-io/goop/goop"
+	"github.com/nitrous-io/goop/pkg/config"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -11,3 +20,35 @@ func Test(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "goop")
 }
+
+var tempDirs []string
+
+var _ = AfterEach(func() {
+	for _, dir := range tempDirs {
+		os.RemoveAll(dir)
+	}
+	tempDirs = nil
+})
+
+// tempDir creates a directory that is removed after the current spec.
+func tempDir() string {
+	dir, err := ioutil.TempDir("", "goop")
+	Expect(err).To(BeNil())
+	tempDirs = append(tempDirs, dir)
+	return dir
+}
+
+// newTestGoop returns a Goop for the project in dir with the default
+// configuration, writing its output to out and discarding errors.
+func newTestGoop(dir string, out io.Writer) *goop.Goop {
+	return goop.NewGoop(dir, config.Default(), nil, colors.NewWriter(out, false), colors.NewWriter(ioutil.Discard, false))
+}
+
+// gitIn runs git in dir and returns its trimmed output.
+func gitIn(dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Goop", "-c", "user.email=goop@example.com"}, args...)...)
+	cmd.Dir = dir
+	b, err := cmd.Output()
+	Expect(err).To(BeNil())
+	return strings.TrimSpace(string(b))
+}
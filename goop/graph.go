@@ -0,0 +1,204 @@
+package goop
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/nitrous-io/goop/parser"
+)
+
+type UnknownGraphFormatError struct {
+	Format string
+}
+
+func (e *UnknownGraphFormatError) Error() string {
+	return fmt.Sprintf("unknown graph format %q; use dot, tree or json", e.Format)
+}
+
+type NotInGraphError struct {
+	Pkg string
+}
+
+func (e *NotInGraphError) Error() string {
+	return fmt.Sprintf("%s is not required by any Goopfile entry", e.Pkg)
+}
+
+// Graph records which sub-dependencies were pulled in by each Goopfile entry.
+// Roots are the direct dependencies; edges point from a package to the
+// transitive dependencies it introduced.
+type Graph struct {
+	Roots []string            `json:"roots"`
+	Edges map[string][]string `json:"edges"`
+	Revs  map[string]string   `json:"revs"`
+}
+
+func NewGraph() *Graph {
+	return &Graph{Roots: []string{}, Edges: map[string][]string{}, Revs: map[string]string{}}
+}
+
+// NewGraphFromDeps builds a graph from the provenance recorded on locked
+// dependencies. Lock files written before provenance was recorded yield a
+// graph with no edges.
+func NewGraphFromDeps(deps []*parser.Dependency) *Graph {
+	gr := NewGraph()
+	for _, dep := range deps {
+		if dep.Direct() {
+			gr.AddRoot(dep.Pkg, dep.Rev)
+		}
+	}
+	for _, dep := range deps {
+		for _, parent := range dep.Parents {
+			gr.AddEdge(parent, dep.Pkg, dep.Rev)
+		}
+	}
+	return gr
+}
+
+func (gr *Graph) AddRoot(pkg string, rev string) {
+	for _, r := range gr.Roots {
+		if r == pkg {
+			return
+		}
+	}
+	gr.Roots = append(gr.Roots, pkg)
+	gr.Revs[pkg] = rev
+}
+
+func (gr *Graph) AddEdge(parent string, child string, rev string) {
+	for _, c := range gr.Edges[parent] {
+		if c == child {
+			return
+		}
+	}
+	gr.Edges[parent] = append(gr.Edges[parent], child)
+	sort.Strings(gr.Edges[parent])
+	gr.Revs[child] = rev
+}
+
+// Nodes returns every package in the graph, sorted.
+func (gr *Graph) Nodes() []string {
+	nodes := make([]string, 0, len(gr.Revs))
+	for k := range gr.Revs {
+		nodes = append(nodes, k)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// Paths returns every chain of packages leading from a root to pkg. A node
+// matches pkg if it is pkg itself or a parent import path of pkg.
+func (gr *Graph) Paths(pkg string) [][]string {
+	var paths [][]string
+	var walk func(node string, chain []string)
+	walk = func(node string, chain []string) {
+		for _, n := range chain {
+			if n == node {
+				return
+			}
+		}
+		chain = append(chain, node)
+		if node == pkg || strings.HasPrefix(pkg, node+"/") {
+			paths = append(paths, append([]string{}, chain...))
+			return
+		}
+		for _, child := range gr.Edges[node] {
+			walk(child, chain)
+		}
+	}
+	for _, root := range gr.Roots {
+		walk(root, nil)
+	}
+	return paths
+}
+
+func (gr *Graph) WriteWhy(w io.Writer, pkg string) error {
+	paths := gr.Paths(pkg)
+	if len(paths) == 0 {
+		return &NotInGraphError{Pkg: pkg}
+	}
+	var blocks []string
+	for _, chain := range paths {
+		lines := make([]string, 0, len(chain))
+		for i, node := range chain {
+			if i == 0 {
+				lines = append(lines, gr.label(node))
+			} else {
+				lines = append(lines, strings.Repeat("  ", i)+"-> "+gr.label(node))
+			}
+		}
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+	_, err := io.WriteString(w, strings.Join(blocks, "\n\n")+"\n")
+	return err
+}
+
+func (gr *Graph) Write(w io.Writer, format string) error {
+	switch format {
+	case "dot":
+		return gr.WriteDOT(w)
+	case "tree", "":
+		return gr.WriteTree(w)
+	case "json":
+		return gr.WriteJSON(w)
+	}
+	return &UnknownGraphFormatError{Format: format}
+}
+
+func (gr *Graph) WriteDOT(w io.Writer) error {
+	lines := []string{"digraph goop {"}
+	for _, pkg := range gr.Nodes() {
+		lines = append(lines, fmt.Sprintf("  %q [label=%q];", pkg, gr.label(pkg)))
+	}
+	for _, parent := range gr.Nodes() {
+		for _, child := range gr.Edges[parent] {
+			lines = append(lines, fmt.Sprintf("  %q -> %q;", parent, child))
+		}
+	}
+	lines = append(lines, "}")
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func (gr *Graph) WriteTree(w io.Writer) error {
+	var lines []string
+	var walk func(pkg string, depth int, seen map[string]bool)
+	walk = func(pkg string, depth int, seen map[string]bool) {
+		lines = append(lines, strings.Repeat("  ", depth)+gr.label(pkg))
+		if seen[pkg] {
+			// cyclic dependency; do not descend again
+			return
+		}
+		seen[pkg] = true
+		for _, child := range gr.Edges[pkg] {
+			walk(child, depth+1, seen)
+		}
+		delete(seen, pkg)
+	}
+	for _, root := range gr.Roots {
+		walk(root, 0, map[string]bool{})
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func (gr *Graph) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(gr, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func (gr *Graph) label(pkg string) string {
+	if rev := gr.Revs[pkg]; rev != "" {
+		return pkg + " #" + rev
+	}
+	return pkg
+}
@@ -0,0 +1,117 @@
+package goop_test
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/nitrous-io/goop/goop"
+	"github.com/nitrous-io/goop/parser"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("graph", func() {
+	var (
+		gr  *goop.Graph
+		buf *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		gr = goop.NewGraph()
+		gr.AddRoot("github.com/onsi/ginkgo/ginkgo", "aaa")
+		gr.AddRoot("github.com/nitrous-io/foo", "bbb")
+		gr.AddEdge("github.com/nitrous-io/foo", "github.com/nitrous-io/bar", "ccc")
+		gr.AddEdge("github.com/nitrous-io/foo", "github.com/nitrous-io/baz", "ddd")
+		gr.AddEdge("github.com/nitrous-io/foo", "github.com/nitrous-io/bar", "ccc")
+		buf = &bytes.Buffer{}
+	})
+
+	Describe("NewGraphFromDeps()", func() {
+		It("uses direct dependencies as roots and parents as edges", func() {
+			gr2 := goop.NewGraphFromDeps([]*parser.Dependency{
+				{Pkg: "github.com/nitrous-io/bar", Rev: "ccc", Parents: []string{"github.com/nitrous-io/foo"}},
+				{Pkg: "github.com/nitrous-io/baz", Rev: "ddd", Parents: []string{"github.com/nitrous-io/foo"}},
+				{Pkg: "github.com/nitrous-io/foo", Rev: "bbb"},
+				{Pkg: "github.com/onsi/ginkgo/ginkgo", Rev: "aaa"},
+			})
+			Expect(gr2.Roots).To(Equal([]string{"github.com/nitrous-io/foo", "github.com/onsi/ginkgo/ginkgo"}))
+			Expect(gr2.Edges).To(Equal(gr.Edges))
+			Expect(gr2.Revs).To(Equal(gr.Revs))
+		})
+	})
+
+	Describe("WriteTree()", func() {
+		It("prints each root followed by its indented sub-dependencies", func() {
+			Expect(gr.WriteTree(buf)).To(Succeed())
+			Expect(buf.String()).To(Equal(`github.com/onsi/ginkgo/ginkgo #aaa
+github.com/nitrous-io/foo #bbb
+  github.com/nitrous-io/bar #ccc
+  github.com/nitrous-io/baz #ddd
+`))
+		})
+	})
+
+	Describe("WriteDOT()", func() {
+		It("prints a graphviz digraph", func() {
+			Expect(gr.WriteDOT(buf)).To(Succeed())
+			Expect(buf.String()).To(HavePrefix("digraph goop {\n"))
+			Expect(buf.String()).To(ContainSubstring(`"github.com/nitrous-io/bar" [label="github.com/nitrous-io/bar #ccc"];`))
+			Expect(buf.String()).To(ContainSubstring(`"github.com/nitrous-io/foo" -> "github.com/nitrous-io/baz";`))
+			Expect(buf.String()).To(HaveSuffix("}\n"))
+		})
+	})
+
+	Describe("WriteJSON()", func() {
+		It("prints the roots, edges and revisions", func() {
+			Expect(gr.WriteJSON(buf)).To(Succeed())
+			gr2 := &goop.Graph{}
+			Expect(json.Unmarshal(buf.Bytes(), gr2)).To(Succeed())
+			Expect(gr2).To(Equal(gr))
+		})
+	})
+
+	Describe("Paths()", func() {
+		BeforeEach(func() {
+			gr.AddEdge("github.com/onsi/ginkgo/ginkgo", "github.com/nitrous-io/bar", "ccc")
+		})
+
+		It("returns every chain from a root to the package", func() {
+			Expect(gr.Paths("github.com/nitrous-io/bar")).To(Equal([][]string{
+				{"github.com/onsi/ginkgo/ginkgo", "github.com/nitrous-io/bar"},
+				{"github.com/nitrous-io/foo", "github.com/nitrous-io/bar"},
+			}))
+		})
+
+		It("matches sub-packages of a node", func() {
+			Expect(gr.Paths("github.com/nitrous-io/baz/qux")).To(Equal([][]string{
+				{"github.com/nitrous-io/foo", "github.com/nitrous-io/baz"},
+			}))
+		})
+
+		It("returns nothing for an unknown package", func() {
+			Expect(gr.Paths("github.com/nitrous-io/nope")).To(BeEmpty())
+		})
+	})
+
+	Describe("WriteWhy()", func() {
+		It("prints each chain with revisions", func() {
+			Expect(gr.WriteWhy(buf, "github.com/nitrous-io/baz")).To(Succeed())
+			Expect(buf.String()).To(Equal(`github.com/nitrous-io/foo #bbb
+  -> github.com/nitrous-io/baz #ddd
+`))
+		})
+
+		It("fails for a package that is not in the graph", func() {
+			err := gr.WriteWhy(buf, "github.com/nitrous-io/nope")
+			Expect(err).To(BeAssignableToTypeOf(&goop.NotInGraphError{}))
+		})
+	})
+
+	Describe("Write()", func() {
+		It("fails for an unknown format", func() {
+			err := gr.Write(buf, "svg")
+			Expect(err).To(BeAssignableToTypeOf(&goop.UnknownGraphFormatError{}))
+		})
+	})
+})
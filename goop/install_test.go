@@ -0,0 +1,37 @@
+package goop_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path"
+
+	"github.com/nitrous-io/goop/goop"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Install()", func() {
+	var (
+		dir string
+		out *bytes.Buffer
+		g   *goop.Goop
+	)
+
+	BeforeEach(func() {
+		dir = tempDir()
+		out = &bytes.Buffer{}
+		g = newTestGoop(dir, out)
+	})
+
+	It("leaves lock files in an older format alone", func() {
+		lockPath := path.Join(dir, "Goopfile.lock")
+		Expect(ioutil.WriteFile(lockPath, []byte("// no dependencies\n"), 0644)).To(Succeed())
+
+		Expect(g.Install()).To(Succeed())
+		b, err := ioutil.ReadFile(lockPath)
+		Expect(err).To(BeNil())
+		Expect(string(b)).To(Equal("// no dependencies\n"))
+		Expect(out.String()).To(ContainSubstring(`run "goop update" to upgrade it`))
+	})
+})
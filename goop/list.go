@@ -0,0 +1,188 @@
+package goop
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nitrous-io/goop/parser"
+)
+
+type NotLockedError struct {
+	Pkg string
+}
+
+func (e *NotLockedError) Error() string {
+	return fmt.Sprintf("%s is not in Goopfile.lock", e.Pkg)
+}
+
+// List prints every package in Goopfile.lock with its revision, version
+// control system, repository URL and whether it is a direct or transitive
+// dependency. It only reads Goopfile.lock, so it works offline.
+func (g *Goop) List() error {
+	lock, err := g.readLock()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(g.stdout, 0, 4, 2, ' ', 0)
+	for _, dep := range lock.Deps {
+		repo, err := g.lockedRepo(dep)
+		if err != nil {
+			return err
+		}
+		kind := "direct"
+		if !dep.Direct() {
+			kind = "transitive (" + strings.Join(dep.Parents, ", ") + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", dep.Pkg, dep.Rev, repo.VCS.Cmd, repo.Repo, kind)
+	}
+	return w.Flush()
+}
+
+// Show prints the details of the locked package pkg: where it is checked out,
+// the checked out revision against the locked one, its last commit and the
+// Goopfile line that required it.
+func (g *Goop) Show(pkg string) error {
+	lock, err := g.readLock()
+	if err != nil {
+		return err
+	}
+	var dep *parser.Dependency
+	for _, d := range lock.Deps {
+		if d.Pkg == pkg {
+			dep = d
+		}
+	}
+	if dep == nil {
+		return &NotLockedError{Pkg: pkg}
+	}
+
+	repo, err := g.lockedRepo(dep)
+	if err != nil {
+		return err
+	}
+	pkgPath := path.Join(g.vendorDir(), "src", repo.Root)
+
+	w := tabwriter.NewWriter(g.stdout, 0, 4, 1, ' ', 0)
+	fmt.Fprintf(w, "Package:\t%s\n", dep.Pkg)
+	fmt.Fprintf(w, "Repository:\t%s (%s)\n", repo.Repo, repo.VCS.Cmd)
+	fmt.Fprintf(w, "Path:\t%s\n", pkgPath)
+	fmt.Fprintf(w, "Locked:\t%s\n", dep.Rev)
+
+	exists, err := pathExists(pkgPath)
+	if err != nil {
+		return err
+	}
+	if exists {
+		rev, err := g.currentRev(dep.Pkg, repo.VCS.Cmd, pkgPath)
+		if err != nil {
+			return err
+		}
+		if rev == resolveRev(repo.VCS.Cmd, pkgPath, dep.Rev) {
+			fmt.Fprintf(w, "Checked out:\t%s\n", rev)
+		} else {
+			fmt.Fprintf(w, "Checked out:\t%s (does not match Goopfile.lock)\n", rev)
+		}
+		date, subject, err := g.lastCommit(dep.Pkg, repo.VCS.Cmd, pkgPath)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Last commit:\t%s %s\n", date, subject)
+	} else {
+		fmt.Fprintf(w, "Checked out:\tnot installed\n")
+	}
+
+	if dep.Direct() {
+		ln, line, err := g.goopfileLine(dep.Pkg)
+		if err != nil {
+			return err
+		}
+		if ln > 0 {
+			fmt.Fprintf(w, "Goopfile:\tline %d: %s\n", ln, line)
+		} else {
+			fmt.Fprintf(w, "Goopfile:\tnot listed\n")
+		}
+	} else {
+		fmt.Fprintf(w, "Required by:\t%s\n", strings.Join(dep.Parents, ", "))
+	}
+	return w.Flush()
+}
+
+// lastCommit returns the date and subject of the commit checked out at path.
+func (g *Goop) lastCommit(pkg string, vcsCmd string, path string) (string, string, error) {
+	var cmd *exec.Cmd
+	switch vcsCmd {
+	case "git":
+		cmd = exec.Command("git", "log", "-1", "--format=%ci%n%s", "HEAD")
+	case "hg":
+		cmd = exec.Command("hg", "log", "-r", ".", "--template", "{date|isodate}\n{desc|firstline}")
+	default:
+		return "", "", &UnsupportedVCSError{VCS: vcsCmd}
+	}
+	cmd.Dir = path
+	_, stderr, done := g.childOutput(pkg)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	err = done(err)
+	if err != nil {
+		return "", "", err
+	}
+	lines := strings.SplitN(strings.TrimSpace(string(out)), "\n", 2)
+	if len(lines) < 2 {
+		return lines[0], "", nil
+	}
+	return lines[0], lines[1], nil
+}
+
+// resolveRev returns the commit rev names in the repository at path, or rev
+// itself if it cannot be resolved.
+func resolveRev(vcsCmd string, path string, rev string) string {
+	var cmd *exec.Cmd
+	switch vcsCmd {
+	case "git":
+		cmd = exec.Command("git", "rev-parse", "--verify", "-q", rev+"^{commit}")
+	case "hg":
+		cmd = exec.Command("hg", "log", "-r", rev, "--template", "{node}")
+	default:
+		return rev
+	}
+	cmd.Dir = path
+	out, err := cmd.Output()
+	if err != nil {
+		return rev
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// goopfileLine returns the number and text of the Goopfile line listing pkg,
+// or 0 if no line does.
+func (g *Goop) goopfileLine(pkg string) (uint, string, error) {
+	f, err := os.Open(path.Join(g.dir, "Goopfile"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, "", nil
+		}
+		return 0, "", err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	ln := uint(0)
+	for s.Scan() {
+		ln++
+		line := strings.TrimSpace(s.Text())
+		if parser.IsScript(line) {
+			continue
+		}
+		tokens := strings.Fields(line)
+		if len(tokens) > 0 && tokens[0] == pkg {
+			return ln, line, nil
+		}
+	}
+	return 0, "", s.Err()
+}
@@ -0,0 +1,108 @@
+package goop_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/nitrous-io/goop/colors"
+	"github.com/nitrous-io/goop/goop"
+	"github.com/nitrous-io/goop/pkg/config"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("List() and Show()", func() {
+	var (
+		dir     string
+		repoDir string
+		rev     string
+		out     *bytes.Buffer
+		g       *goop.Goop
+	)
+
+	BeforeEach(func() {
+		dir = tempDir()
+		out = &bytes.Buffer{}
+		g = newTestGoop(dir, out)
+
+		repoDir = path.Join(dir, ".vendor/src/github.com/nitrous-io/a")
+		Expect(os.MkdirAll(repoDir, 0775)).To(Succeed())
+		gitIn(repoDir, "init", "-q")
+		Expect(ioutil.WriteFile(path.Join(repoDir, "a.go"), []byte("package a\n"), 0644)).To(Succeed())
+		gitIn(repoDir, "add", "a.go")
+		gitIn(repoDir, "commit", "-q", "-m", "Initial import")
+		rev = gitIn(repoDir, "rev-parse", "HEAD")
+
+		Expect(ioutil.WriteFile(path.Join(dir, "Goopfile"), []byte("// deps\ngithub.com/nitrous-io/a #"+rev+"\n"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(path.Join(dir, "Goopfile.lock"), []byte(`{
+			"version": 2,
+			"dependencies": [
+				{"package": "github.com/nitrous-io/a", "rev": "`+rev+`", "vcs": "git", "root": "github.com/nitrous-io/a"},
+				{"package": "github.com/nitrous-io/b", "rev": "v1.0", "vcs": "git", "root": "github.com/nitrous-io/b", "parents": ["github.com/nitrous-io/a"]}
+			]
+		}`), 0644)).To(Succeed())
+	})
+
+	Describe("List()", func() {
+		It("prints each locked package with its revision, VCS, URL and kind", func() {
+			Expect(g.List()).To(Succeed())
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			Expect(lines).To(HaveLen(2))
+			Expect(strings.Fields(lines[0])).To(Equal([]string{"github.com/nitrous-io/a", rev, "git", "https://github.com/nitrous-io/a", "direct"}))
+			Expect(strings.Fields(lines[1])).To(Equal([]string{"github.com/nitrous-io/b", "v1.0", "git", "https://github.com/nitrous-io/b", "transitive", "(github.com/nitrous-io/a)"}))
+		})
+
+		It("prints canonical URLs without looking up repositories", func() {
+			cfg := config.Default()
+			cfg.Rewrites = map[string]string{"github.com/": "https://git.example.com/mirror/github.com/"}
+			g = goop.NewGoop(dir, cfg, nil, colors.NewWriter(out, false), colors.NewWriter(ioutil.Discard, false))
+			Expect(ioutil.WriteFile(path.Join(dir, "Goopfile.lock"), []byte(`{
+				"version": 2,
+				"dependencies": [
+					{"package": "github.com/nitrous-io/a", "rev": "v1", "vcs": "git", "root": "github.com/nitrous-io/a"},
+					{"package": "goop.invalid/pkg/sub", "rev": "v1", "vcs": "hg", "root": "goop.invalid/pkg"},
+					{"package": "goop.invalid/fork", "rev": "v1", "url": "git@example.com:fork.git", "vcs": "git", "root": "goop.invalid/fork"}
+				]
+			}`), 0644)).To(Succeed())
+
+			Expect(g.List()).To(Succeed())
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			Expect(strings.Fields(lines[0])).To(Equal([]string{"github.com/nitrous-io/a", "v1", "git", "https://github.com/nitrous-io/a", "direct"}))
+			Expect(strings.Fields(lines[1])).To(Equal([]string{"goop.invalid/pkg/sub", "v1", "hg", "goop.invalid/pkg", "direct"}))
+			Expect(strings.Fields(lines[2])).To(Equal([]string{"goop.invalid/fork", "v1", "git", "git@example.com:fork.git", "direct"}))
+		})
+	})
+
+	Describe("Show()", func() {
+		It("prints the checkout, last commit and Goopfile line of a direct dependency", func() {
+			Expect(g.Show("github.com/nitrous-io/a")).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("Path:        " + repoDir + "\n"))
+			Expect(out.String()).To(ContainSubstring("Checked out: " + rev + "\n"))
+			Expect(out.String()).To(MatchRegexp(`Last commit: \d{4}-\d\d-\d\d .* Initial import\n`))
+			Expect(out.String()).To(ContainSubstring("Goopfile:    line 2: github.com/nitrous-io/a #" + rev + "\n"))
+		})
+
+		It("reports a checkout that does not match Goopfile.lock", func() {
+			Expect(ioutil.WriteFile(path.Join(repoDir, "b.go"), []byte("package a\n"), 0644)).To(Succeed())
+			gitIn(repoDir, "add", "b.go")
+			gitIn(repoDir, "commit", "-q", "-m", "Add b")
+			Expect(g.Show("github.com/nitrous-io/a")).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("(does not match Goopfile.lock)"))
+		})
+
+		It("prints what required a transitive dependency", func() {
+			Expect(g.Show("github.com/nitrous-io/b")).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("Checked out: not installed\n"))
+			Expect(out.String()).To(ContainSubstring("Required by: github.com/nitrous-io/a\n"))
+		})
+
+		It("fails for packages that are not locked", func() {
+			err := g.Show("github.com/nitrous-io/c")
+			Expect(err).To(BeAssignableToTypeOf(&goop.NotLockedError{}))
+		})
+	})
+})
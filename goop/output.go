@@ -0,0 +1,94 @@
+package goop
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"sync"
+)
+
+// PrefixWriter writes each line written to it to an underlying writer,
+// prefixed with "[prefix] ". Lines are written whole, so output from several
+// PrefixWriters sharing a writer does not interleave mid-line.
+type PrefixWriter struct {
+	mu     sync.Mutex
+	prefix []byte
+	writer io.Writer
+	buf    []byte
+}
+
+func NewPrefixWriter(writer io.Writer, prefix string) *PrefixWriter {
+	return &PrefixWriter{prefix: []byte("[" + prefix + "] "), writer: writer}
+}
+
+func (w *PrefixWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		err = w.writeLine(w.buf[:i+1])
+		w.buf = w.buf[i+1:]
+		if err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes any incomplete last line.
+func (w *PrefixWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	err := w.writeLine(append(w.buf, '\n'))
+	w.buf = nil
+	return err
+}
+
+func (w *PrefixWriter) writeLine(line []byte) error {
+	_, err := w.writer.Write(append(append([]byte{}, w.prefix...), line...))
+	return err
+}
+
+// run runs cmd, a child process working on pkg. In verbose mode its output is
+// shown as it is written; otherwise it is held back and only shown on
+// failure. Either way each line is prefixed with pkg.
+func (g *Goop) run(pkg string, cmd *exec.Cmd) error {
+	stdout, stderr, done := g.childOutput(pkg)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return done(cmd.Run())
+}
+
+// childOutput returns the writers for the output of a child process working
+// on pkg, along with a function to call with the error the process exited
+// with, which returns the same error.
+func (g *Goop) childOutput(pkg string) (stdout io.Writer, stderr io.Writer, done func(error) error) {
+	if g.verbosity == Verbose {
+		out := NewPrefixWriter(g.stdout, pkg)
+		errOut := NewPrefixWriter(g.stderr, pkg)
+		return out, errOut, func(err error) error {
+			out.Flush()
+			errOut.Flush()
+			return err
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	out := NewPrefixWriter(buf, pkg)
+	return out, out, func(err error) error {
+		out.Flush()
+		if err != nil {
+			g.stderr.Write(buf.Bytes())
+		}
+		return err
+	}
+}
@@ -0,0 +1,36 @@
+package goop_test
+
+import (
+	"bytes"
+
+	"github.com/nitrous-io/goop/goop"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PrefixWriter", func() {
+	var (
+		buf *bytes.Buffer
+		w   *goop.PrefixWriter
+	)
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+		w = goop.NewPrefixWriter(buf, "github.com/nitrous-io/foo")
+	})
+
+	It("prefixes each line", func() {
+		w.Write([]byte("Cloning into 'foo'...\ndone.\n"))
+		Expect(buf.String()).To(Equal("[github.com/nitrous-io/foo] Cloning into 'foo'...\n[github.com/nitrous-io/foo] done.\n"))
+	})
+
+	It("holds back incomplete lines until they are finished or flushed", func() {
+		w.Write([]byte("WORK=/tmp/go-build"))
+		Expect(buf.String()).To(BeEmpty())
+		w.Write([]byte("123\nmkdir -p $WORK"))
+		Expect(buf.String()).To(Equal("[github.com/nitrous-io/foo] WORK=/tmp/go-build123\n"))
+		Expect(w.Flush()).To(Succeed())
+		Expect(buf.String()).To(Equal("[github.com/nitrous-io/foo] WORK=/tmp/go-build123\n[github.com/nitrous-io/foo] mkdir -p $WORK\n"))
+	})
+})
@@ -0,0 +1,158 @@
+package goop
+
+import (
+	"os"
+	"path"
+
+	"code.google.com/p/go.tools/go/vcs"
+
+	"github.com/nitrous-io/goop/parser"
+)
+
+// PinSource looks up and fetches the dependencies pinned by other
+// dependencies, for ResolvePins.
+type PinSource interface {
+	// Repo resolves the repository of dep.
+	Repo(dep *parser.Dependency) (*vcs.RepoRoot, error)
+
+	// Pins returns the dependencies pinned by the checkout of repo.
+	Pins(repo *vcs.RepoRoot) ([]*parser.Dependency, error)
+
+	// Fetch checks out repo at pin's revision, or at its current revision if
+	// pin has none, and returns the revision checked out. pinnedBy is the
+	// package that pinned it.
+	Fetch(pin *parser.Dependency, repo *vcs.RepoRoot, pinnedBy string) (string, error)
+}
+
+// ResolvePins honors the pins in the Goopfile.lock (or Goopfile) of each of
+// deps, and of each dependency pinned in turn, so that go get does not fetch
+// the latest revisions of their sub-dependencies. Explicit requirements in
+// reqs win over pins; the pins they override are returned. repos and locked
+// map packages to their repository and locked dependency, and pinned
+// dependencies are added to both.
+func ResolvePins(src PinSource, deps []*parser.Dependency, reqs Requirements, repos map[string]*vcs.RepoRoot, locked map[string]*parser.Dependency) ([]string, error) {
+	var overridden []string
+	queue := append([]*parser.Dependency{}, deps...)
+	for len(queue) > 0 {
+		dep := queue[0]
+		queue = queue[1:]
+
+		repo := repos[dep.Pkg]
+		pins, err := src.Pins(repo)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pin := range pins {
+			if pin.Rev == "" && pin.URL == "" {
+				continue
+			}
+
+			pinRepo, err := src.Repo(pin)
+			if err != nil {
+				return nil, err
+			}
+			if pinRepo.Root == repo.Root {
+				continue
+			}
+
+			req := &Requirement{Pkg: pin.Pkg, Rev: pin.Rev, URL: pin.URL, Requester: dep.Pkg}
+			err = reqs.Add(pinRepo.Root, req)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved := reqs.Resolved(pinRepo.Root)
+			if resolved.Explicit {
+				if resolved.conflictsWith(req) {
+					overridden = append(overridden, req.String()+", using "+resolved.String())
+				}
+				continue
+			}
+			if locked[pin.Pkg] != nil {
+				locked[pin.Pkg].AddParent(dep.Pkg)
+				continue
+			}
+
+			rev, err := src.Fetch(pin, pinRepo, dep.Pkg)
+			if err != nil {
+				return nil, err
+			}
+
+			pinned := &parser.Dependency{Pkg: pin.Pkg, Rev: rev, URL: pin.URL, Parents: []string{dep.Pkg}}
+			repos[pin.Pkg] = pinRepo
+			locked[pin.Pkg] = pinned
+			queue = append(queue, pinned)
+		}
+	}
+	return overridden, nil
+}
+
+// ReadNestedDeps returns the dependencies pinned by the Goopfile.lock in
+// dir, falling back to its Goopfile. It returns nil if neither exists.
+func ReadNestedDeps(dir string) ([]*parser.Dependency, error) {
+	f, err := os.Open(path.Join(dir, "Goopfile.lock"))
+	if err == nil {
+		defer f.Close()
+		lock, err := parser.ParseLock(f)
+		if err != nil {
+			return nil, err
+		}
+		return lock.Deps, nil
+	}
+
+	f, err = os.Open(path.Join(dir, "Goopfile"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return parser.Parse(f)
+}
+
+// pinFetcher is the PinSource used while installing, which fetches pinned
+// dependencies into the temporary GOPATH.
+type pinFetcher struct {
+	g          *Goop
+	tmpSrcPath string
+}
+
+func (f *pinFetcher) Repo(dep *parser.Dependency) (*vcs.RepoRoot, error) {
+	return f.g.repoForDep(dep)
+}
+
+func (f *pinFetcher) Pins(repo *vcs.RepoRoot) ([]*parser.Dependency, error) {
+	return ReadNestedDeps(path.Join(f.tmpSrcPath, repo.Root))
+}
+
+func (f *pinFetcher) Fetch(pin *parser.Dependency, repo *vcs.RepoRoot, pinnedBy string) (string, error) {
+	g := f.g
+	g.progress("=> Fetching " + pin.Pkg + " pinned by " + pinnedBy + "...")
+
+	pinPkgPath := path.Join(f.tmpSrcPath, repo.Root)
+	exists, err := pathExists(pinPkgPath)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		err = os.MkdirAll(path.Join(pinPkgPath, ".."), 0775)
+		if err != nil {
+			return "", err
+		}
+		err = g.clone(pin.Pkg, repo.VCS.Cmd, repo.Repo, pinPkgPath)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	rev := pin.Rev
+	if rev == "" {
+		rev, err = g.currentRev(pin.Pkg, repo.VCS.Cmd, pinPkgPath)
+		if err != nil {
+			return "", err
+		}
+	}
+	return rev, g.checkout(pin.Pkg, repo.VCS.Cmd, repo.Repo, pinPkgPath, rev)
+}
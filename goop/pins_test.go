@@ -0,0 +1,153 @@
+package goop_test
+
+import (
+	"io/ioutil"
+	"path"
+
+	"code.google.com/p/go.tools/go/vcs"
+
+	"github.com/nitrous-io/goop/goop"
+	"github.com/nitrous-io/goop/parser"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakePinSource serves pins by repository root and records what is fetched.
+type fakePinSource struct {
+	pins    map[string][]*parser.Dependency
+	fetched []string
+}
+
+func (s *fakePinSource) Repo(dep *parser.Dependency) (*vcs.RepoRoot, error) {
+	return goop.RepoRootForImportPathWithURLOverride(dep.Pkg, "https://"+dep.Pkg)
+}
+
+func (s *fakePinSource) Pins(repo *vcs.RepoRoot) ([]*parser.Dependency, error) {
+	return s.pins[repo.Root], nil
+}
+
+func (s *fakePinSource) Fetch(pin *parser.Dependency, repo *vcs.RepoRoot, pinnedBy string) (string, error) {
+	s.fetched = append(s.fetched, pin.Pkg+" #"+pin.Rev+" <"+pinnedBy)
+	return pin.Rev, nil
+}
+
+var _ = Describe("pins", func() {
+	Describe("ResolvePins()", func() {
+		var (
+			src    *fakePinSource
+			deps   []*parser.Dependency
+			reqs   goop.Requirements
+			repos  map[string]*vcs.RepoRoot
+			locked map[string]*parser.Dependency
+		)
+
+		BeforeEach(func() {
+			src = &fakePinSource{pins: map[string][]*parser.Dependency{}}
+			reqs = goop.NewRequirements()
+			repos = map[string]*vcs.RepoRoot{}
+			locked = map[string]*parser.Dependency{}
+			deps = nil
+		})
+
+		addDep := func(pkg string, rev string) {
+			dep := &parser.Dependency{Pkg: pkg, Rev: rev}
+			repo, err := src.Repo(dep)
+			Expect(err).To(BeNil())
+			Expect(reqs.Add(repo.Root, &goop.Requirement{Pkg: pkg, Rev: rev, Requester: "Goopfile", Explicit: true})).To(Succeed())
+			deps = append(deps, dep)
+			repos[pkg] = repo
+			locked[pkg] = dep
+		}
+
+		It("fetches the revisions pinned by dependencies", func() {
+			addDep("github.com/nitrous-io/a", "v1")
+			src.pins["github.com/nitrous-io/a"] = []*parser.Dependency{
+				{Pkg: "github.com/nitrous-io/b", Rev: "v2"},
+				{Pkg: "github.com/nitrous-io/unpinned"},
+			}
+
+			overridden, err := goop.ResolvePins(src, deps, reqs, repos, locked)
+			Expect(err).To(BeNil())
+			Expect(overridden).To(BeEmpty())
+			Expect(src.fetched).To(Equal([]string{"github.com/nitrous-io/b #v2 <github.com/nitrous-io/a"}))
+			Expect(locked["github.com/nitrous-io/b"]).To(Equal(&parser.Dependency{Pkg: "github.com/nitrous-io/b", Rev: "v2", Parents: []string{"github.com/nitrous-io/a"}}))
+			Expect(repos["github.com/nitrous-io/b"].Root).To(Equal("github.com/nitrous-io/b"))
+		})
+
+		It("lets Goopfile entries override pins, and reports them", func() {
+			addDep("github.com/nitrous-io/a", "v1")
+			addDep("github.com/nitrous-io/b", "v3")
+			src.pins["github.com/nitrous-io/a"] = []*parser.Dependency{{Pkg: "github.com/nitrous-io/b", Rev: "v2"}}
+
+			overridden, err := goop.ResolvePins(src, deps, reqs, repos, locked)
+			Expect(err).To(BeNil())
+			Expect(overridden).To(Equal([]string{"github.com/nitrous-io/b #v2 (required by github.com/nitrous-io/a), using github.com/nitrous-io/b #v3 (required by Goopfile)"}))
+			Expect(src.fetched).To(BeEmpty())
+			Expect(locked["github.com/nitrous-io/b"].Rev).To(Equal("v3"))
+		})
+
+		It("skips pins inside the pinning dependency's own repository", func() {
+			addDep("github.com/nitrous-io/a", "v1")
+			src.pins["github.com/nitrous-io/a"] = []*parser.Dependency{{Pkg: "github.com/nitrous-io/a/sub", Rev: "v0"}}
+
+			_, err := goop.ResolvePins(src, deps, reqs, repos, locked)
+			Expect(err).To(BeNil())
+			Expect(src.fetched).To(BeEmpty())
+			Expect(locked).NotTo(HaveKey("github.com/nitrous-io/a/sub"))
+		})
+
+		It("follows chains of pins", func() {
+			addDep("github.com/nitrous-io/a", "v1")
+			src.pins["github.com/nitrous-io/a"] = []*parser.Dependency{{Pkg: "github.com/nitrous-io/b", Rev: "v2"}}
+			src.pins["github.com/nitrous-io/b"] = []*parser.Dependency{{Pkg: "github.com/nitrous-io/c", Rev: "v3"}}
+
+			_, err := goop.ResolvePins(src, deps, reqs, repos, locked)
+			Expect(err).To(BeNil())
+			Expect(src.fetched).To(Equal([]string{
+				"github.com/nitrous-io/b #v2 <github.com/nitrous-io/a",
+				"github.com/nitrous-io/c #v3 <github.com/nitrous-io/b",
+			}))
+			Expect(locked["github.com/nitrous-io/c"].Parents).To(Equal([]string{"github.com/nitrous-io/b"}))
+		})
+
+		It("fails when pins conflict", func() {
+			addDep("github.com/nitrous-io/a", "v1")
+			addDep("github.com/nitrous-io/b", "v1")
+			src.pins["github.com/nitrous-io/a"] = []*parser.Dependency{{Pkg: "github.com/nitrous-io/c", Rev: "v2"}}
+			src.pins["github.com/nitrous-io/b"] = []*parser.Dependency{{Pkg: "github.com/nitrous-io/c", Rev: "v3"}}
+
+			_, err := goop.ResolvePins(src, deps, reqs, repos, locked)
+			Expect(err).To(BeAssignableToTypeOf(&goop.ConflictError{}))
+		})
+	})
+
+	Describe("ReadNestedDeps()", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir = tempDir()
+		})
+
+		It("reads Goopfile.lock in preference to Goopfile", func() {
+			Expect(ioutil.WriteFile(path.Join(dir, "Goopfile"), []byte("github.com/nitrous-io/a\n"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(path.Join(dir, "Goopfile.lock"), []byte(`{"version": 2, "dependencies": [{"package": "github.com/nitrous-io/a", "rev": "v1"}]}`), 0644)).To(Succeed())
+			deps, err := goop.ReadNestedDeps(dir)
+			Expect(err).To(BeNil())
+			Expect(deps).To(Equal([]*parser.Dependency{{Pkg: "github.com/nitrous-io/a", Rev: "v1"}}))
+		})
+
+		It("falls back to Goopfile", func() {
+			Expect(ioutil.WriteFile(path.Join(dir, "Goopfile"), []byte("github.com/nitrous-io/a #v2\n"), 0644)).To(Succeed())
+			deps, err := goop.ReadNestedDeps(dir)
+			Expect(err).To(BeNil())
+			Expect(deps).To(Equal([]*parser.Dependency{{Pkg: "github.com/nitrous-io/a", Rev: "v2"}}))
+		})
+
+		It("returns nil when neither exists", func() {
+			deps, err := goop.ReadNestedDeps(dir)
+			Expect(err).To(BeNil())
+			Expect(deps).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,71 @@
+package goop
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nitrous-io/goop/pkg/env"
+)
+
+// URLRewrites maps import path prefixes to the URL prefixes repositories
+// under them are fetched from, e.g. "github.com/*" to
+// "https://git.example.com/mirror/github.com/*". A trailing * on either side
+// is optional.
+type URLRewrites map[string]string
+
+// Rewrite returns the URL to fetch the repository at the import path root
+// from, using the rule with the longest matching prefix. Prefixes only match
+// whole path elements, so "github.com/foo" does not match
+// "github.com/foobar".
+func (r URLRewrites) Rewrite(root string) (string, bool) {
+	best := ""
+	for prefix := range r {
+		p := strings.TrimSuffix(prefix, "*")
+		if matchesPrefix(root, p) && len(p) > len(strings.TrimSuffix(best, "*")) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+	p := strings.TrimSuffix(best, "*")
+	return strings.TrimSuffix(r[best], "*") + root[len(p):], true
+}
+
+func matchesPrefix(root string, p string) bool {
+	if !strings.HasPrefix(root, p) {
+		return false
+	}
+	return p == "" || len(root) == len(p) || strings.HasSuffix(p, "/") || root[len(p)] == '/'
+}
+
+// GitEnv adds url.<base>.insteadOf settings to e, so that repositories
+// fetched by go get over HTTPS are also fetched from their rewritten URLs.
+// git matches insteadOf as a plain string prefix, so both sides are ended
+// with a slash to match whole path elements as Rewrite does.
+func (r URLRewrites) GitEnv(e env.Env) {
+	prefixes := make([]string, 0, len(r))
+	for prefix := range r {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	count, _ := strconv.Atoi(e["GIT_CONFIG_COUNT"])
+	for _, prefix := range prefixes {
+		base := strings.TrimSuffix(r[prefix], "*")
+		p := strings.TrimSuffix(prefix, "*")
+		if p != "" && !strings.HasSuffix(p, "/") {
+			p += "/"
+			if !strings.HasSuffix(base, "/") {
+				base += "/"
+			}
+		}
+		e["GIT_CONFIG_KEY_"+strconv.Itoa(count)] = "url." + base + ".insteadOf"
+		e["GIT_CONFIG_VALUE_"+strconv.Itoa(count)] = "https://" + p
+		count++
+	}
+	if count > 0 {
+		e["GIT_CONFIG_COUNT"] = strconv.Itoa(count)
+	}
+}
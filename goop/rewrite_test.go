@@ -0,0 +1,75 @@
+package goop_test
+
+import (
+	"strings"
+
+	"github.com/nitrous-io/goop/goop"
+	"github.com/nitrous-io/goop/pkg/env"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("rewrite", func() {
+	rewrites := goop.URLRewrites{
+		"github.com/*":           "https://git.example.com/mirror/github.com/*",
+		"github.com/nitrous-io/": "git@git.example.com:nitrous-io/",
+	}
+
+	Describe("Rewrite()", func() {
+		It("rewrites using the longest matching prefix", func() {
+			url, ok := rewrites.Rewrite("github.com/onsi/ginkgo")
+			Expect(ok).To(BeTrue())
+			Expect(url).To(Equal("https://git.example.com/mirror/github.com/onsi/ginkgo"))
+
+			url, ok = rewrites.Rewrite("github.com/nitrous-io/goop")
+			Expect(ok).To(BeTrue())
+			Expect(url).To(Equal("git@git.example.com:nitrous-io/goop"))
+		})
+
+		It("leaves other import paths alone", func() {
+			_, ok := rewrites.Rewrite("bitbucket.org/kardianos/osext")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("only matches whole path elements", func() {
+			r := goop.URLRewrites{"github.com/foo": "https://git.example.com/foo"}
+
+			url, ok := r.Rewrite("github.com/foo")
+			Expect(ok).To(BeTrue())
+			Expect(url).To(Equal("https://git.example.com/foo"))
+
+			url, ok = r.Rewrite("github.com/foo/bar")
+			Expect(ok).To(BeTrue())
+			Expect(url).To(Equal("https://git.example.com/foo/bar"))
+
+			_, ok = r.Rewrite("github.com/foobar/x")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("GitEnv()", func() {
+		It("adds insteadOf settings for each rule", func() {
+			e := env.Env{"GIT_CONFIG_COUNT": "1"}
+			rewrites.GitEnv(e)
+			Expect(e).To(Equal(env.Env{
+				"GIT_CONFIG_COUNT":   "3",
+				"GIT_CONFIG_KEY_1":   "url.https://git.example.com/mirror/github.com/.insteadOf",
+				"GIT_CONFIG_VALUE_1": "https://github.com/",
+				"GIT_CONFIG_KEY_2":   "url.git@git.example.com:nitrous-io/.insteadOf",
+				"GIT_CONFIG_VALUE_2": "https://github.com/nitrous-io/",
+			}))
+		})
+
+		It("ends rules at a path element boundary", func() {
+			e := env.Env{}
+			goop.URLRewrites{"github.com/foo": "https://git.example.com/foo"}.GitEnv(e)
+			Expect(e).To(Equal(env.Env{
+				"GIT_CONFIG_COUNT":   "1",
+				"GIT_CONFIG_KEY_0":   "url.https://git.example.com/foo/.insteadOf",
+				"GIT_CONFIG_VALUE_0": "https://github.com/foo/",
+			}))
+			Expect(strings.HasPrefix("https://github.com/foobar/x", e["GIT_CONFIG_VALUE_0"])).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,66 @@
+package goop
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/nitrous-io/goop/parser"
+)
+
+type UnknownScriptError struct {
+	Name string
+}
+
+func (e *UnknownScriptError) Error() string {
+	return fmt.Sprintf("no script named %q in Goopfile", e.Name)
+}
+
+// Run runs the Goopfile script called name with sh, as Exec does. args are
+// appended to the script's command.
+func (g *Goop) Run(name string, args ...string) error {
+	goopfile, err := g.readGoopfile()
+	if err != nil {
+		return err
+	}
+	script := goopfile.Script(name)
+	if script == nil {
+		return &UnknownScriptError{Name: name}
+	}
+	return g.Exec("sh", append([]string{"-c", script.Command + ` "$@"`, name}, args...)...)
+}
+
+// PrintScripts prints the scripts in the Goopfile.
+func (g *Goop) PrintScripts() error {
+	goopfile, err := g.readGoopfile()
+	if err != nil {
+		return err
+	}
+	for _, s := range goopfile.Scripts {
+		g.stdout.Write([]byte(s.Name + ": " + s.Command + "\n"))
+	}
+	return nil
+}
+
+func (g *Goop) readGoopfile() (*parser.Goopfile, error) {
+	f, err := os.Open(path.Join(g.dir, "Goopfile"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parser.ParseGoopfile(f)
+}
+
+// goopfileHash hashes the Goopfile b without its scripts, so that editing
+// scripts does not make Goopfile.lock look out of date.
+func goopfileHash(b []byte) string {
+	lines := strings.SplitAfter(string(b), "\n")
+	kept := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if !parser.IsScript(l) {
+			kept = append(kept, l)
+		}
+	}
+	return contentHash([]byte(strings.Join(kept, "")))
+}
@@ -0,0 +1,59 @@
+package goop_test
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/nitrous-io/goop/goop"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("scripts", func() {
+	var (
+		dir string
+		out *bytes.Buffer
+		g   *goop.Goop
+	)
+
+	BeforeEach(func() {
+		dir = tempDir()
+		out = &bytes.Buffer{}
+		g = newTestGoop(dir, out)
+
+		lock := []byte(`{"version": 2, "dependencies": []}`)
+		state := fmt.Sprintf(`{"lock_sha1": "%x", "revs": {}}`, sha1.Sum(lock))
+		Expect(ioutil.WriteFile(path.Join(dir, "Goopfile"), []byte("script greet: echo hello\nscript gobin: echo $GOBIN\n"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(path.Join(dir, "Goopfile.lock"), lock, 0644)).To(Succeed())
+		Expect(os.MkdirAll(path.Join(dir, ".vendor"), 0775)).To(Succeed())
+		Expect(ioutil.WriteFile(path.Join(dir, ".vendor", ".goop-state"), []byte(state), 0644)).To(Succeed())
+	})
+
+	Describe("Run()", func() {
+		It("runs the script with the given arguments appended", func() {
+			Expect(g.Run("greet", "big world")).To(Succeed())
+			Expect(out.String()).To(Equal("hello big world\n"))
+		})
+
+		It("runs the script in the vendored environment", func() {
+			Expect(g.Run("gobin")).To(Succeed())
+			Expect(out.String()).To(Equal(dir + "/.vendor/bin\n"))
+		})
+
+		It("fails for unknown scripts", func() {
+			Expect(g.Run("deploy")).To(BeAssignableToTypeOf(&goop.UnknownScriptError{}))
+		})
+	})
+
+	Describe("PrintScripts()", func() {
+		It("lists the scripts", func() {
+			Expect(g.PrintScripts()).To(Succeed())
+			Expect(out.String()).To(Equal("greet: echo hello\ngobin: echo $GOBIN\n"))
+		})
+	})
+})
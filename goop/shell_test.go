@@ -0,0 +1,53 @@
+package goop_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/nitrous-io/goop/goop"
+	"github.com/nitrous-io/goop/pkg/env"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Shell()", func() {
+	var (
+		dir   string
+		out   *bytes.Buffer
+		g     *goop.Goop
+		shell string
+	)
+
+	BeforeEach(func() {
+		dir = tempDir()
+		out = &bytes.Buffer{}
+		g = newTestGoop(dir, out)
+		g.SetVerbosity(goop.Quiet)
+
+		// a "shell" that prints what an interactive one would show
+		shell = os.Getenv("SHELL")
+		sh := path.Join(dir, "shell")
+		Expect(ioutil.WriteFile(sh, []byte("#!/bin/sh\necho \"$GOOP_ACTIVE $GOBIN\"\n"), 0755)).To(Succeed())
+		os.Setenv("SHELL", sh)
+	})
+
+	AfterEach(func() {
+		os.Setenv("SHELL", shell)
+	})
+
+	It("runs $SHELL with the vendored environment and GOOP_ACTIVE", func() {
+		Expect(g.Shell()).To(Succeed())
+		Expect(out.String()).To(Equal(dir + " " + dir + "/.vendor/bin\n"))
+	})
+
+	It("refuses to start in a shell set up by goop env", func() {
+		os.Setenv(env.ActiveVar, "/project/.vendor")
+		defer os.Unsetenv(env.ActiveVar)
+		err := g.Shell()
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(ContainSubstring("goop env --unset"))
+	})
+})
@@ -0,0 +1,143 @@
+package goop
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/nitrous-io/goop/parser"
+)
+
+// stateFile records what was installed in the vendor directory, relative to
+// the vendor directory.
+const stateFile = ".goop-state"
+
+// State is what goop install last installed in the vendor directory.
+type State struct {
+	// LockHash is the SHA-1 of the contents of Goopfile.lock installed from.
+	LockHash string `json:"lock_sha1"`
+
+	// Revs maps each installed package to its revision.
+	Revs map[string]string `json:"revs"`
+}
+
+// StaleVendorError is returned by Exec when the vendor directory does not
+// match Goopfile.lock. Pkgs lists the locked packages that are not installed
+// at their locked revision, when that is known.
+type StaleVendorError struct {
+	Reason string
+	Pkgs   []string
+}
+
+func (e *StaleVendorError) Error() string {
+	return e.Reason + `; run "goop install" first, or use --auto-install`
+}
+
+// SetAutoInstall sets whether Exec runs Install when the vendor directory
+// does not match Goopfile.lock, instead of failing.
+func (g *Goop) SetAutoInstall(autoInstall bool) {
+	g.autoInstall = autoInstall
+}
+
+// checkInstalled returns a *StaleVendorError if the vendor directory does not
+// match Goopfile.lock, or runs Install to bring it up to date if auto-install
+// is on. Projects without a Goopfile are not checked.
+func (g *Goop) checkInstalled() error {
+	err := g.staleness()
+	if err == nil || !g.autoInstall {
+		return err
+	}
+	if _, ok := err.(*StaleVendorError); !ok {
+		return err
+	}
+	g.progress("=> " + err.(*StaleVendorError).Reason + "; installing...")
+	return g.Install()
+}
+
+func (g *Goop) staleness() error {
+	lockBytes, err := ioutil.ReadFile(path.Join(g.dir, "Goopfile.lock"))
+	if os.IsNotExist(err) {
+		exists, err := pathExists(path.Join(g.dir, "Goopfile"))
+		if err != nil || !exists {
+			return err
+		}
+		return &StaleVendorError{Reason: "Goopfile.lock not found"}
+	}
+	if err != nil {
+		return err
+	}
+
+	state, err := g.readState()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return &StaleVendorError{Reason: "dependencies in " + g.vendorDir() + " have not been fully installed"}
+	}
+	if state.LockHash != contentHash(lockBytes) {
+		staleErr := &StaleVendorError{Reason: "Goopfile.lock has changed since dependencies were installed"}
+		lock, err := parser.ParseLock(bytes.NewReader(lockBytes))
+		if err != nil {
+			return err
+		}
+		for _, dep := range lock.Deps {
+			if state.Revs[dep.Pkg] != dep.Rev {
+				staleErr.Pkgs = append(staleErr.Pkgs, dep.Pkg)
+			}
+		}
+		if len(staleErr.Pkgs) > 0 {
+			staleErr.Reason += " (out of date: " + strings.Join(staleErr.Pkgs, ", ") + ")"
+		}
+		return staleErr
+	}
+	return nil
+}
+
+// readState returns the state of the vendor directory, or nil if it is
+// unknown.
+func (g *Goop) readState() (*State, error) {
+	b, err := ioutil.ReadFile(path.Join(g.vendorDir(), stateFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	state := &State{}
+	err = json.Unmarshal(b, state)
+	if err != nil {
+		// an unreadable state just means it is unknown
+		return nil, nil
+	}
+	return state, nil
+}
+
+// writeState records deps as installed from the current Goopfile.lock.
+func (g *Goop) writeState(deps []*parser.Dependency) error {
+	lockBytes, err := ioutil.ReadFile(path.Join(g.dir, "Goopfile.lock"))
+	if err != nil {
+		return err
+	}
+	state := &State{LockHash: contentHash(lockBytes), Revs: map[string]string{}}
+	for _, dep := range deps {
+		state.Revs[dep.Pkg] = dep.Rev
+	}
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path.Join(g.vendorDir(), stateFile), append(b, '\n'), 0644)
+}
+
+// clearState forgets what was installed, so that an interrupted install is
+// not mistaken for a complete one.
+func (g *Goop) clearState() error {
+	err := os.Remove(path.Join(g.vendorDir(), stateFile))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -1,89 +1,192 @@
 package main
 
 import (
-	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path"
-	"strconv"
 	"strings"
 
 	"github.com/nitrous-io/goop/colors"
 	"github.com/nitrous-io/goop/goop"
+	"github.com/nitrous-io/goop/pkg/config"
+)
+
+// exit code for invalid command lines
+const usageExitCode = 2
+
+var name = path.Base(os.Args[0])
+
+// output is colored only on terminals until the flags say otherwise
+var (
+	stdout = colors.NewWriter(os.Stdout, colors.Auto.Enabled(os.Stdout))
+	stderr = colors.NewWriter(os.Stderr, colors.Auto.Enabled(os.Stderr))
 )
 
 func main() {
-	name := path.Base(os.Args[0])
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	dir := flags.String("C", "", "")
+	verbose := flags.Bool("verbose", false, "")
+	quiet := flags.Bool("quiet", false, "")
+	color := flags.String("color", "auto", "")
+	noColor := flags.Bool("no-color", false, "")
+	vendorDir := flags.String("vendor-dir", "", "")
 
-	pwd, err := os.Getwd()
+	err := flags.Parse(os.Args[1:])
 	if err != nil {
-		os.Stderr.WriteString(colors.Error + name + ": failed to determine present working directory!" + colors.Reset + "\n")
+		usageError(nil, err.Error())
 	}
+	colorMode, err := colors.ParseMode(*color)
+	if err != nil {
+		usageError(nil, err.Error())
+	}
+	if *noColor {
+		colorMode = colors.Never
+	}
+	stdout = colors.NewWriter(os.Stdout, colorMode.Enabled(os.Stdout))
+	stderr = colors.NewWriter(os.Stderr, colorMode.Enabled(os.Stderr))
 
-	g := goop.NewGoop(path.Join(pwd), os.Stdin, os.Stdout, os.Stderr)
+	if flags.NArg() < 1 {
+		printUsage(stderr)
+		os.Exit(usageExitCode)
+	}
+	if *verbose && *quiet {
+		usageError(nil, "--verbose and --quiet cannot be used together")
+	}
 
-	if len(os.Args) < 2 {
-		printUsage()
+	cmdName := flags.Arg(0)
+	if cmdName == "help" {
+		help(flags.Args()[1:])
+		return
+	}
+	cmd := lookupCommand(cmdName)
+	if cmd == nil {
+		usageError(nil, `unrecognized command "`+cmdName+`"`)
 	}
 
-	cmd := os.Args[1]
-	switch cmd {
-	case "help":
-		printUsage()
-	case "install":
-		err = g.Install()
-	case "update":
-		err = g.Update()
-	case "exec":
-		if len(os.Args) < 3 {
-			printUsage()
+	pwd, err := os.Getwd()
+	if err != nil {
+		fail(nil, "failed to determine present working directory!", 1)
+	}
+	if *dir != "" {
+		if !path.IsAbs(*dir) {
+			*dir = path.Join(pwd, *dir)
 		}
-		err = g.Exec(os.Args[2], os.Args[3:]...)
-	case "go":
-		if len(os.Args) < 3 {
-			printUsage()
+		pwd = *dir
+	}
+
+	cfg, err := config.Load(pwd)
+	if err != nil {
+		fail(nil, err.Error(), 1)
+	}
+	if *vendorDir != "" {
+		err = cfg.Set("vendor_dir", *vendorDir, "--vendor-dir")
+		if err != nil {
+			fail(nil, err.Error(), 1)
 		}
-		err = g.Exec("go", os.Args[2:]...)
-	case "env":
-		g.PrintEnv()
-	default:
-		err = errors.New(`unrecognized command "` + cmd + `"`)
 	}
 
+	// default flags from the configuration go before the given arguments
+	args := append(append([]string{}, cfg.Flags[cmd.Name()]...), flags.Args()[1:]...)
+	cmd.Flag.SetOutput(ioutil.Discard)
+	err = cmd.Flag.Parse(args)
 	if err != nil {
-		errMsg := err.Error()
-		code := 1
+		usageError(cmd, err.Error())
+	}
+	args = cmd.Flag.Args()
+
+	g := goop.NewGoop(pwd, cfg, os.Stdin, stdout, stderr)
+	switch {
+	case *verbose:
+		g.SetVerbosity(goop.Verbose)
+	case *quiet:
+		g.SetVerbosity(goop.Quiet)
+	}
 
-		// go does not provide a cross-platform way to get exit status, so inspect error message instead
-		// https://code.google.com/p/go/source/browse/src/pkg/os/exec_posix.go#119
-		if strings.HasPrefix(errMsg, "exit status ") {
-			code, err = strconv.Atoi(errMsg[len("exit status "):])
-			if err != nil {
-				code = 1
-			}
-			errMsg = "Command failed with " + errMsg
+	err = cmd.Run(cmd, g, args)
+	if err != nil {
+		if uerr, ok := err.(*UsageError); ok {
+			usageError(cmd, uerr.Message)
 		}
 
-		os.Stderr.WriteString(colors.Error + name + ": " + errMsg + colors.Reset + "\n")
-		os.Exit(code)
+		code := 1
+		if eerr, ok := err.(*goop.ExitError); ok {
+			code = eerr.ExitCode()
+		}
+		fail(cmd, err.Error(), code)
+	}
+}
+
+// fail prints msg and exits with code.
+func fail(cmd *Command, msg string, code int) {
+	prefix := name
+	if cmd != nil {
+		prefix += " " + cmd.Name()
 	}
+	stderr.Error(prefix + ": " + msg)
+	os.Exit(code)
 }
 
-func printUsage() {
-	os.Stdout.WriteString(strings.TrimSpace(usage) + "\n\n")
-	os.Exit(0)
+// usageError prints msg followed by how to use cmd (or goop itself, if cmd is
+// nil) and exits with usageExitCode.
+func usageError(cmd *Command, msg string) {
+	prefix := name
+	usage := "Run '" + name + " help' for usage."
+	if cmd != nil {
+		prefix += " " + cmd.Name()
+		usage = "usage: " + name + " " + cmd.UsageLine + "\nRun '" + name + " help " + cmd.Name() + "' for details."
+	}
+	stderr.Error(prefix + ": " + msg)
+	io.WriteString(stderr, usage+"\n")
+	os.Exit(usageExitCode)
 }
 
-const usage = `
-Goop is a tool for managing Go dependencies.
+func help(args []string) {
+	if len(args) == 0 {
+		printUsage(stdout)
+		return
+	}
+	if len(args) > 1 {
+		usageError(nil, "help takes at most one command")
+	}
 
-        goop command [arguments]
+	cmd := lookupCommand(args[0])
+	if cmd == nil {
+		usageError(nil, `unknown help topic "`+args[0]+`"`)
+	}
+	fmt.Fprintf(stdout, "usage: %s %s\n\n%s\n", name, cmd.UsageLine, strings.TrimSpace(cmd.Long))
+}
+
+func printUsage(w io.Writer) {
+	lines := []string{
+		"Goop is a tool for managing Go dependencies.",
+		"",
+		"        " + name + " [global flags] command [arguments]",
+		"",
+		"The commands are:",
+		"",
+	}
+	for _, cmd := range commands {
+		lines = append(lines, fmt.Sprintf("    %-11s %s", cmd.Name(), cmd.Short))
+	}
+	lines = append(lines, fmt.Sprintf("    %-11s %s", "help", "print this message, or help for a command"))
+	lines = append(lines, strings.Split(strings.TrimRight(globalUsage, "\n"), "\n")...)
+	lines = append(lines, "", "Use \""+name+" help [command]\" for more information about a command.")
+	io.WriteString(w, strings.Join(lines, "\n")+"\n\n")
+}
 
-The commands are:
+const globalUsage = `
+The global flags are:
 
-    install     install the dependencies specified by Goopfile or Goopfile.lock
-    update      update dependencies to their latest versions
-    env         print GOPATH and PATH environment variables, with the vendor path prepended
-    exec        execute a command in the context of the installed dependencies
-    go          execute a go command in the context of the installed dependencies
-    help        print this message
+    -C dir            run as if goop was started in dir
+    --verbose         also print the output of the commands goop runs
+    --quiet           only print warnings and errors
+    --color mode      color output: auto (only on terminals, unless NO_COLOR
+                      is set), always or never
+    --no-color        same as --color=never
+    --vendor-dir dir  install dependencies in dir instead of .vendor (also
+                      GOOP_VENDOR_DIR, or vendor_dir in .goop.toml)
 `
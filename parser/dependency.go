@@ -3,13 +3,35 @@ package parser
 import "strings"
 
 type Dependency struct {
-	Pkg string
-	Rev string
-	URL string
+	Pkg string `json:"package"`
+	Rev string `json:"rev"`
+	URL string `json:"url,omitempty"`
+
+	// VCS and Root are the version control system and repository root the
+	// package was resolved to. They are only recorded in Goopfile.lock.
+	VCS  string `json:"vcs,omitempty"`
+	Root string `json:"root,omitempty"`
+
+	// Parents lists the packages that introduced a transitive dependency. It
+	// is empty for dependencies listed directly in the Goopfile.
+	Parents []string `json:"parents,omitempty"`
+}
+
+func (d *Dependency) Direct() bool {
+	return len(d.Parents) == 0
+}
+
+func (d *Dependency) AddParent(parent string) {
+	for _, p := range d.Parents {
+		if p == parent {
+			return
+		}
+	}
+	d.Parents = append(d.Parents, parent)
 }
 
 func (d *Dependency) String() string {
-	s := make([]string, 0, 3)
+	s := make([]string, 0, 3+len(d.Parents))
 	s = append(s, d.Pkg)
 	if d.Rev != "" {
 		s = append(s, "#"+d.Rev)
@@ -17,5 +39,8 @@ func (d *Dependency) String() string {
 	if d.URL != "" {
 		s = append(s, "!"+d.URL)
 	}
+	for _, p := range d.Parents {
+		s = append(s, "<"+p)
+	}
 	return strings.Join(s, " ")
 }
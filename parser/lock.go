@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// LockVersion is the format version written to Goopfile.lock. Version 1 is
+// the original line-based format shared with the Goopfile.
+const LockVersion = 2
+
+type UnsupportedLockVersionError struct {
+	Version int
+}
+
+func (e *UnsupportedLockVersionError) Error() string {
+	return fmt.Sprintf("Goopfile.lock format version %d is not supported by this version of goop (expected %d or lower)", e.Version, LockVersion)
+}
+
+type Lock struct {
+	Version int `json:"version"`
+
+	// GoopfileHash is the SHA-1 of the Goopfile the lock was generated from.
+	GoopfileHash string `json:"goopfile_sha1,omitempty"`
+
+	Deps []*Dependency `json:"dependencies"`
+}
+
+func NewLock(deps []*Dependency) *Lock {
+	return &Lock{Version: LockVersion, Deps: deps}
+}
+
+// ParseLock reads a Goopfile.lock in either the structured format or the
+// legacy line-based format.
+func ParseLock(r io.Reader) (*Lock, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		g, err := parseGoopfile(bytes.NewReader(b), true)
+		if err != nil {
+			return nil, err
+		}
+		return &Lock{Version: 1, Deps: g.Deps}, nil
+	}
+
+	lock := &Lock{}
+	err = json.Unmarshal(b, lock)
+	if err != nil {
+		return nil, err
+	}
+	if lock.Version < 2 || lock.Version > LockVersion {
+		return nil, &UnsupportedLockVersionError{Version: lock.Version}
+	}
+	if lock.Deps == nil {
+		lock.Deps = []*Dependency{}
+	}
+	return lock, nil
+}
+
+// Write writes the lock in the structured format, upgrading it to
+// LockVersion if it was read from an older format.
+func (l *Lock) Write(w io.Writer) error {
+	lock := *l
+	lock.Version = LockVersion
+	b, err := json.MarshalIndent(&lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
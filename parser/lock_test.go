@@ -0,0 +1,105 @@
+package parser_test
+
+import (
+	"bytes"
+
+	"github.com/nitrous-io/goop/parser"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("lock", func() {
+	Describe("ParseLock()", func() {
+		var (
+			lock *parser.Lock
+			err  error
+		)
+
+		Context("legacy line-based lock", func() {
+			BeforeEach(func() {
+				lock, err = parser.ParseLock(bytes.NewBufferString(`
+					github.com/gorilla/context #14f550f51af52180c2eefed15e5fd18d63c0a64a <github.com/gorilla/mux
+					github.com/gorilla/mux #ffffffffffffffffffffffffffffffffffffffff
+				`))
+			})
+
+			It("parses the dependencies as format version 1", func() {
+				Expect(err).To(BeNil())
+				Expect(lock.Version).To(Equal(1))
+				Expect(lock.Deps).To(Equal([]*parser.Dependency{
+					{Pkg: "github.com/gorilla/context", Rev: "14f550f51af52180c2eefed15e5fd18d63c0a64a", Parents: []string{"github.com/gorilla/mux"}},
+					{Pkg: "github.com/gorilla/mux", Rev: "ffffffffffffffffffffffffffffffffffffffff"},
+				}))
+			})
+
+			It("round-trips parents through String()", func() {
+				Expect(lock.Deps[0].String()).To(Equal("github.com/gorilla/context #14f550f51af52180c2eefed15e5fd18d63c0a64a <github.com/gorilla/mux"))
+				Expect(lock.Deps[0].Direct()).To(BeFalse())
+			})
+
+			It("fails for an empty parent", func() {
+				_, err := parser.ParseLock(bytes.NewBufferString("github.com/gorilla/context <\n"))
+				Expect(err).To(BeAssignableToTypeOf(&parser.ParseError{}))
+			})
+		})
+
+		Context("structured lock", func() {
+			BeforeEach(func() {
+				lock, err = parser.ParseLock(bytes.NewBufferString(`{
+					"version": 2,
+					"goopfile_sha1": "abc",
+					"dependencies": [
+						{
+							"package": "github.com/gorilla/mux",
+							"rev": "ffffffffffffffffffffffffffffffffffffffff",
+							"url": "git@github.com:nitrous-io/mux.git",
+							"vcs": "git",
+							"root": "github.com/gorilla/mux"
+						}
+					]
+				}`))
+			})
+
+			It("parses the lock", func() {
+				Expect(err).To(BeNil())
+				Expect(lock).To(Equal(&parser.Lock{
+					Version:      2,
+					GoopfileHash: "abc",
+					Deps: []*parser.Dependency{{
+						Pkg:  "github.com/gorilla/mux",
+						Rev:  "ffffffffffffffffffffffffffffffffffffffff",
+						URL:  "git@github.com:nitrous-io/mux.git",
+						VCS:  "git",
+						Root: "github.com/gorilla/mux",
+					}},
+				}))
+			})
+		})
+
+		Context("structured lock from a newer version of goop", func() {
+			BeforeEach(func() {
+				lock, err = parser.ParseLock(bytes.NewBufferString(`{"version": 99, "dependencies": []}`))
+			})
+
+			It("fails and returns an unsupported version error", func() {
+				Expect(err).To(BeAssignableToTypeOf(&parser.UnsupportedLockVersionError{}))
+				Expect(lock).To(BeNil())
+			})
+		})
+	})
+
+	Describe("Write()", func() {
+		It("writes a legacy lock in the current format", func() {
+			lock, err := parser.ParseLock(bytes.NewBufferString("github.com/gorilla/mux #ffff\n"))
+			Expect(err).To(BeNil())
+
+			buf := &bytes.Buffer{}
+			Expect(lock.Write(buf)).To(Succeed())
+
+			lock2, err := parser.ParseLock(buf)
+			Expect(err).To(BeNil())
+			Expect(lock2.Version).To(Equal(parser.LockVersion))
+			Expect(lock2.Deps).To(Equal(lock.Deps))
+		})
+	})
+})
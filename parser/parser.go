@@ -22,16 +22,61 @@ const (
 	TokenComment = "//"
 	TokenRev     = "#"
 	TokenURL     = "!"
+	TokenParent  = "<"
+	TokenScript  = "script"
 )
 
 func (e *ParseError) Error() string {
 	return fmt.Sprintf("Parse failed at line %d - %s\n  %s", e.LineNum, e.LineText, e.Message)
 }
 
+// Goopfile is the contents of a Goopfile.
+type Goopfile struct {
+	Deps    []*Dependency
+	Scripts []*Script
+}
+
+// Script is a named command, given in a Goopfile as "script name: command".
+type Script struct {
+	Name    string
+	Command string
+}
+
+// Script returns the script called name, or nil if there is none.
+func (g *Goopfile) Script(name string) *Script {
+	for _, s := range g.Scripts {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// IsScript reports whether line of a Goopfile defines a script.
+func IsScript(line string) bool {
+	tokens := strings.Fields(line)
+	return len(tokens) > 1 && tokens[0] == TokenScript
+}
+
+// Parse returns the dependencies in a Goopfile, ignoring scripts.
 func Parse(r io.Reader) ([]*Dependency, error) {
+	g, err := ParseGoopfile(r)
+	if err != nil {
+		return nil, err
+	}
+	return g.Deps, nil
+}
+
+func ParseGoopfile(r io.Reader) (*Goopfile, error) {
+	return parseGoopfile(r, false)
+}
+
+// parseGoopfile parses a Goopfile, or a legacy line-based Goopfile.lock if
+// isLock is set, in which parents may be given with "<".
+func parseGoopfile(r io.Reader, isLock bool) (*Goopfile, error) {
 	s := bufio.NewScanner(r)
 	ln := uint(0)
-	deps := []*Dependency{}
+	g := &Goopfile{Deps: []*Dependency{}, Scripts: []*Script{}}
 
 	for s.Scan() {
 		ln++
@@ -42,6 +87,18 @@ func Parse(r io.Reader) ([]*Dependency, error) {
 			continue
 		}
 
+		if IsScript(line) {
+			script, err := parseScript(line, ln)
+			if err != nil {
+				return nil, err
+			}
+			if g.Script(script.Name) != nil {
+				return nil, &ParseError{LineNum: ln, LineText: line, Message: "Duplicate script name"}
+			}
+			g.Scripts = append(g.Scripts, script)
+			continue
+		}
+
 		dep := &Dependency{Pkg: tokens[0]}
 		parseErr := &ParseError{LineNum: ln, LineText: line}
 
@@ -62,16 +119,50 @@ func Parse(r io.Reader) ([]*Dependency, error) {
 					return nil, parseErr
 				}
 				dep.URL = t[1:]
+			case strings.HasPrefix(t, TokenParent):
+				if !isLock {
+					parseErr.Message = "Parents can only be given in Goopfile.lock"
+					return nil, parseErr
+				}
+				if len(t) == 1 {
+					parseErr.Message = "Empty parent given"
+					return nil, parseErr
+				}
+				dep.AddParent(t[1:])
 			default:
 				parseErr.Message = "Unrecognized token given"
 				return nil, parseErr
 			}
 		}
-		deps = append(deps, dep)
+		g.Deps = append(g.Deps, dep)
 	}
 
 	if err := s.Err(); err != nil {
 		return nil, err
 	}
-	return deps, nil
+	return g, nil
+}
+
+// parseScript parses a script line. Everything after the colon is the
+// command, including anything that looks like a comment.
+func parseScript(line string, ln uint) (*Script, error) {
+	parseErr := &ParseError{LineNum: ln, LineText: line}
+
+	rest := strings.TrimSpace(line[len(TokenScript):])
+	i := strings.Index(rest, ":")
+	if i < 0 {
+		parseErr.Message = "Script name must be followed by a colon"
+		return nil, parseErr
+	}
+	name := strings.TrimSpace(rest[:i])
+	if name == "" || strings.ContainsAny(name, " \t") {
+		parseErr.Message = "Invalid script name"
+		return nil, parseErr
+	}
+	command := strings.TrimSpace(rest[i+1:])
+	if command == "" {
+		parseErr.Message = "Empty script given"
+		return nil, parseErr
+	}
+	return &Script{Name: name, Command: command}, nil
 }
@@ -91,6 +91,19 @@ var _ = Describe("parser", func() {
 				})
 			})
 
+			Context("with parents", func() {
+				BeforeEach(func() {
+					deps, err = parser.Parse(bytes.NewBufferString(`
+						github.com/gorilla/context <github.com/gorilla/mux
+					`))
+				})
+
+				It("fails and returns parse error, as parents are only recorded in Goopfile.lock", func() {
+					Expect(err).To(BeAssignableToTypeOf(&parser.ParseError{}))
+					Expect(deps).To(BeNil())
+				})
+			})
+
 			Context("with a comment", func() {
 				BeforeEach(func() {
 					deps, err = parser.Parse(bytes.NewBufferString(`
@@ -155,4 +168,38 @@ var _ = Describe("parser", func() {
 			})
 		})
 	})
+
+	Describe("ParseGoopfile()", func() {
+		It("parses scripts alongside dependencies", func() {
+			g, err := parser.ParseGoopfile(bytes.NewBufferString(`
+				github.com/nitrous-io/goop #09f0feb1b103933bd9985f0a85e01eeaad8d75c8
+				script test: ginkgo -r
+				// scripts can use anything the shell understands
+				script serve:  go run main.go --addr=http://localhost:8080 // not a comment
+			`))
+			Expect(err).To(BeNil())
+			Expect(g.Deps).To(Equal([]*parser.Dependency{
+				{Pkg: "github.com/nitrous-io/goop", Rev: "09f0feb1b103933bd9985f0a85e01eeaad8d75c8"},
+			}))
+			Expect(g.Scripts).To(Equal([]*parser.Script{
+				{Name: "test", Command: "ginkgo -r"},
+				{Name: "serve", Command: "go run main.go --addr=http://localhost:8080 // not a comment"},
+			}))
+			Expect(g.Script("test").Command).To(Equal("ginkgo -r"))
+			Expect(g.Script("deploy")).To(BeNil())
+		})
+
+		It("is ignored by Parse()", func() {
+			deps, err := parser.Parse(bytes.NewBufferString("script test: ginkgo -r\ngithub.com/gorilla/mux\n"))
+			Expect(err).To(BeNil())
+			Expect(deps).To(Equal([]*parser.Dependency{{Pkg: "github.com/gorilla/mux"}}))
+		})
+
+		It("fails for malformed scripts", func() {
+			for _, line := range []string{"script test ginkgo", "script : ginkgo", "script test:", "script test: a\nscript test: b"} {
+				_, err := parser.ParseGoopfile(bytes.NewBufferString(line))
+				Expect(err).To(BeAssignableToTypeOf(&parser.ParseError{}))
+			}
+		})
+	})
 })
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nitrous-io/goop/pkg/env"
+)
+
+const (
+	// GlobalFile is the user's configuration file, relative to $HOME.
+	GlobalFile = ".goop/config"
+
+	// ProjectFile is the project's configuration file, relative to the
+	// directory containing the Goopfile.
+	ProjectFile = ".goop.toml"
+)
+
+// Config holds goop's settings. Relative paths are relative to the project
+// directory.
+type Config struct {
+	VendorDir   string
+	CacheDir    string
+	Parallelism int
+
+	// AutoInstall makes goop exec run goop install when the vendor
+	// directory does not match Goopfile.lock, instead of failing.
+	AutoInstall bool
+
+	// Isolated makes goop exec use only the vendor directory and the
+	// project itself as GOPATH.
+	Isolated bool
+
+	// Rewrites maps import path prefixes to the URL prefixes they should be
+	// fetched from.
+	Rewrites map[string]string
+
+	// Flags maps command names to arguments inserted before the arguments
+	// given on the command line.
+	Flags map[string][]string
+
+	// Sources records where each setting was last set: "default", a file
+	// name or an environment variable.
+	Sources map[string]string
+}
+
+func Default() *Config {
+	return &Config{
+		VendorDir:   ".vendor",
+		CacheDir:    "",
+		Parallelism: 1,
+		Rewrites:    map[string]string{},
+		Flags:       map[string][]string{},
+		Sources: map[string]string{
+			"vendor_dir":   "default",
+			"cache_dir":    "default",
+			"parallelism":  "default",
+			"auto_install": "default",
+			"isolated":     "default",
+		},
+	}
+}
+
+// Load returns the effective configuration for the project in dir. Built-in
+// defaults are overridden by ~/.goop/config, then by the project's
+// .goop.toml, then by GOOP_* environment variables. Command line flags are
+// applied on top with Set.
+func Load(dir string) (*Config, error) {
+	c := Default()
+	if home := os.Getenv("HOME"); home != "" {
+		err := c.ReadFile(path.Join(home, GlobalFile))
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := c.ReadFile(path.Join(dir, ProjectFile))
+	if err != nil {
+		return nil, err
+	}
+	err = c.ReadEnv(env.NewEnv())
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ReadFile applies the settings in filename. It is not an error for the file
+// not to exist.
+func (c *Config) ReadFile(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	return c.Read(f, filename)
+}
+
+// Read applies the settings in r, recording source as their origin.
+func (c *Config) Read(r io.Reader, source string) error {
+	tables, err := parseTOML(r)
+	if err != nil {
+		if perr, ok := err.(*ParseError); ok {
+			perr.File = source
+		}
+		return err
+	}
+
+	for name, t := range tables {
+		for k, v := range t {
+			err = c.set(name, k, v, source)
+			if err != nil {
+				return fmt.Errorf("%s: %s", source, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ReadEnv applies GOOP_VENDOR_DIR, GOOP_CACHE_DIR, GOOP_PARALLELISM,
+// GOOP_AUTO_INSTALL and GOOP_ISOLATED.
+func (c *Config) ReadEnv(e env.Env) error {
+	for _, key := range []string{"vendor_dir", "cache_dir", "parallelism", "auto_install", "isolated"} {
+		name := "GOOP_" + strings.ToUpper(key)
+		if v := e[name]; v != "" {
+			err := c.Set(key, v, name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Set applies a top-level setting given as a string, such as a command line
+// flag or environment variable named source.
+func (c *Config) Set(key string, value string, source string) error {
+	var val interface{} = value
+	var err error
+	switch key {
+	case "parallelism":
+		val, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %s is not a number", source, value)
+		}
+	case "auto_install", "isolated":
+		val, err = strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: %s is not true or false", source, value)
+		}
+	}
+	err = c.set("", key, val, source)
+	if err != nil {
+		return fmt.Errorf("%s: %s", source, err)
+	}
+	return nil
+}
+
+func (c *Config) set(tableName string, key string, val interface{}, source string) error {
+	switch tableName {
+	case "":
+		switch key {
+		case "vendor_dir", "cache_dir":
+			s, ok := val.(string)
+			if !ok {
+				return fmt.Errorf("%s must be a string", key)
+			}
+			s = expandHome(s)
+			if key == "vendor_dir" {
+				if s == "" {
+					return fmt.Errorf("vendor_dir must not be empty")
+				}
+				c.VendorDir = s
+			} else {
+				c.CacheDir = s
+			}
+		case "parallelism":
+			n, ok := val.(int64)
+			if !ok || n < 1 {
+				return fmt.Errorf("parallelism must be a positive number")
+			}
+			c.Parallelism = int(n)
+		case "auto_install", "isolated":
+			b, ok := val.(bool)
+			if !ok {
+				return fmt.Errorf("%s must be true or false", key)
+			}
+			if key == "auto_install" {
+				c.AutoInstall = b
+			} else {
+				c.Isolated = b
+			}
+		default:
+			return fmt.Errorf("unknown setting %s", key)
+		}
+		c.Sources[key] = source
+	case "rewrite":
+		s, ok := val.(string)
+		if !ok {
+			return fmt.Errorf("rewrite.%s must be a string", key)
+		}
+		c.Rewrites[key] = s
+		c.Sources["rewrite."+key] = source
+	case "flags":
+		flags, ok := val.([]string)
+		if !ok {
+			return fmt.Errorf("flags.%s must be an array of strings", key)
+		}
+		c.Flags[key] = flags
+		c.Sources["flags."+key] = source
+	default:
+		return fmt.Errorf("unknown table [%s]", tableName)
+	}
+	return nil
+}
+
+// Write prints the settings in configuration file syntax, noting where each
+// one was set.
+func (c *Config) Write(w io.Writer) error {
+	lines := []string{
+		c.line("vendor_dir", "vendor_dir", strconv.Quote(c.VendorDir)),
+		c.line("cache_dir", "cache_dir", strconv.Quote(c.CacheDir)),
+		c.line("parallelism", "parallelism", strconv.Itoa(c.Parallelism)),
+		c.line("auto_install", "auto_install", strconv.FormatBool(c.AutoInstall)),
+		c.line("isolated", "isolated", strconv.FormatBool(c.Isolated)),
+	}
+
+	if len(c.Rewrites) > 0 {
+		lines = append(lines, "", "[rewrite]")
+		for _, k := range sortedKeys(c.Rewrites) {
+			lines = append(lines, c.line("rewrite."+k, quoteKey(k), strconv.Quote(c.Rewrites[k])))
+		}
+	}
+
+	if len(c.Flags) > 0 {
+		lines = append(lines, "", "[flags]")
+		names := make([]string, 0, len(c.Flags))
+		for k := range c.Flags {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			quoted := make([]string, len(c.Flags[k]))
+			for i, f := range c.Flags[k] {
+				quoted[i] = strconv.Quote(f)
+			}
+			lines = append(lines, c.line("flags."+k, quoteKey(k), "["+strings.Join(quoted, ", ")+"]"))
+		}
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func (c *Config) line(source string, key string, val string) string {
+	return key + " = " + val + " # " + c.Sources[source]
+}
+
+func quoteKey(k string) string {
+	if isBareKey(k) {
+		return k
+	}
+	return strconv.Quote(k)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func expandHome(p string) string {
+	if strings.HasPrefix(p, "~/") {
+		return path.Join(os.Getenv("HOME"), p[2:])
+	}
+	return p
+}
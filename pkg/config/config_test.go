@@ -0,0 +1,134 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/nitrous-io/goop/pkg/config"
+	"github.com/nitrous-io/goop/pkg/env"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func Test(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "config")
+}
+
+var _ = Describe("config", func() {
+	var c *config.Config
+
+	BeforeEach(func() {
+		c = config.Default()
+	})
+
+	Describe("Default()", func() {
+		It("returns the built-in defaults", func() {
+			Expect(c.VendorDir).To(Equal(".vendor"))
+			Expect(c.CacheDir).To(BeEmpty())
+			Expect(c.Parallelism).To(Equal(1))
+			Expect(c.Sources["vendor_dir"]).To(Equal("default"))
+		})
+	})
+
+	Describe("Read()", func() {
+		It("applies settings and records their source", func() {
+			err := c.Read(bytes.NewBufferString(`
+				# goop settings
+				vendor_dir = "_vendor"
+				parallelism = 4 # install faster
+
+				[rewrite]
+				"github.com/" = 'https://git.example.com/mirror/github.com/'
+
+				[flags]
+				install = ["--verbose", "--quiet"]
+			`), "/project/.goop.toml")
+			Expect(err).To(BeNil())
+			Expect(c.VendorDir).To(Equal("_vendor"))
+			Expect(c.Parallelism).To(Equal(4))
+			Expect(c.Rewrites).To(Equal(map[string]string{"github.com/": "https://git.example.com/mirror/github.com/"}))
+			Expect(c.Flags).To(Equal(map[string][]string{"install": {"--verbose", "--quiet"}}))
+			Expect(c.Sources["vendor_dir"]).To(Equal("/project/.goop.toml"))
+			Expect(c.Sources["cache_dir"]).To(Equal("default"))
+		})
+
+		It("expands ~ in paths", func() {
+			Expect(c.Read(bytes.NewBufferString(`cache_dir = "~/cache"`), "test")).To(Succeed())
+			Expect(c.CacheDir).To(Equal(os.Getenv("HOME") + "/cache"))
+		})
+
+		It("fails for unknown settings", func() {
+			Expect(c.Read(bytes.NewBufferString(`colour = "red"`), "test")).NotTo(Succeed())
+			Expect(c.Read(bytes.NewBufferString("[nope]\nfoo = 1"), "test")).NotTo(Succeed())
+		})
+
+		It("fails for values of the wrong type", func() {
+			Expect(c.Read(bytes.NewBufferString(`parallelism = "4"`), "test")).NotTo(Succeed())
+			Expect(c.Read(bytes.NewBufferString(`parallelism = 0`), "test")).NotTo(Succeed())
+			Expect(c.Read(bytes.NewBufferString("[flags]\ninstall = \"-v\""), "test")).NotTo(Succeed())
+		})
+
+		It("fails with a parse error for malformed files", func() {
+			err := c.Read(bytes.NewBufferString(`vendor_dir = "unterminated`), "test")
+			Expect(err).To(BeAssignableToTypeOf(&config.ParseError{}))
+			Expect(err.(*config.ParseError).File).To(Equal("test"))
+		})
+	})
+
+	Describe("ReadEnv()", func() {
+		It("overrides settings from GOOP_* variables", func() {
+			Expect(c.Read(bytes.NewBufferString(`vendor_dir = "_vendor"`), "test")).To(Succeed())
+			Expect(c.ReadEnv(env.Env{"GOOP_VENDOR_DIR": "/fast/vendor", "GOOP_PARALLELISM": "8", "GOOP_AUTO_INSTALL": "1", "GOOP_ISOLATED": "true"})).To(Succeed())
+			Expect(c.VendorDir).To(Equal("/fast/vendor"))
+			Expect(c.Parallelism).To(Equal(8))
+			Expect(c.AutoInstall).To(BeTrue())
+			Expect(c.Isolated).To(BeTrue())
+			Expect(c.Sources["vendor_dir"]).To(Equal("GOOP_VENDOR_DIR"))
+		})
+
+		It("fails for invalid values", func() {
+			Expect(c.ReadEnv(env.Env{"GOOP_PARALLELISM": "lots"})).NotTo(Succeed())
+			Expect(c.ReadEnv(env.Env{"GOOP_AUTO_INSTALL": "sometimes"})).NotTo(Succeed())
+		})
+	})
+
+	Describe("Set()", func() {
+		It("overrides a setting from a flag", func() {
+			Expect(c.Set("vendor_dir", "../.vendor", "--vendor-dir")).To(Succeed())
+			Expect(c.VendorDir).To(Equal("../.vendor"))
+			Expect(c.Sources["vendor_dir"]).To(Equal("--vendor-dir"))
+		})
+
+		It("fails for an empty vendor directory", func() {
+			Expect(c.Set("vendor_dir", "", "--vendor-dir")).NotTo(Succeed())
+		})
+	})
+
+	Describe("Write()", func() {
+		It("prints the effective settings with their sources", func() {
+			Expect(c.Read(bytes.NewBufferString("parallelism = 2\n[rewrite]\n\"github.com/\" = \"https://mirror/\""), "/home/me/.goop/config")).To(Succeed())
+			buf := &bytes.Buffer{}
+			Expect(c.Write(buf)).To(Succeed())
+			Expect(buf.String()).To(Equal(`vendor_dir = ".vendor" # default
+cache_dir = "" # default
+parallelism = 2 # /home/me/.goop/config
+auto_install = false # default
+isolated = false # default
+
+[rewrite]
+"github.com/" = "https://mirror/" # /home/me/.goop/config
+`))
+		})
+
+		It("round-trips through Read()", func() {
+			Expect(c.Read(bytes.NewBufferString("[flags]\nexec = [\"--isolated\"]"), "test")).To(Succeed())
+			buf := &bytes.Buffer{}
+			Expect(c.Write(buf)).To(Succeed())
+			c2 := config.Default()
+			Expect(c2.Read(buf, "test")).To(Succeed())
+			Expect(c2.Flags).To(Equal(c.Flags))
+		})
+	})
+})
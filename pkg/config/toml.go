@@ -0,0 +1,196 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type ParseError struct {
+	File     string
+	LineNum  uint
+	LineText string
+	Message  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: parse failed at line %d - %s\n  %s", e.File, e.LineNum, e.LineText, e.Message)
+}
+
+// table maps keys to string, int64, bool or []string values.
+type table map[string]interface{}
+
+// parseTOML parses the subset of TOML used by goop configuration files:
+// [tables] containing bare or quoted keys with string, integer, boolean and
+// single-line string array values. Keys before the first table header are
+// returned in the "" table.
+func parseTOML(r io.Reader) (map[string]table, error) {
+	s := bufio.NewScanner(r)
+	ln := uint(0)
+	tables := map[string]table{"": table{}}
+	current := ""
+
+	for s.Scan() {
+		ln++
+		line := strings.TrimSpace(s.Text())
+		parseErr := &ParseError{LineNum: ln, LineText: line}
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			end := strings.Index(line, "]")
+			if end < 0 || !isComment(line[end+1:]) {
+				parseErr.Message = "Malformed table header"
+				return nil, parseErr
+			}
+			current = strings.TrimSpace(line[1:end])
+			if !isBareKey(current) {
+				parseErr.Message = "Invalid table name"
+				return nil, parseErr
+			}
+			if tables[current] == nil {
+				tables[current] = table{}
+			}
+			continue
+		}
+
+		key, rest, err := parseKey(line)
+		if err != nil {
+			parseErr.Message = err.Error()
+			return nil, parseErr
+		}
+		rest = strings.TrimSpace(rest)
+		if !strings.HasPrefix(rest, "=") {
+			parseErr.Message = "Expected = after key"
+			return nil, parseErr
+		}
+		val, rest, err := parseValue(strings.TrimSpace(rest[1:]))
+		if err != nil {
+			parseErr.Message = err.Error()
+			return nil, parseErr
+		}
+		if !isComment(rest) {
+			parseErr.Message = "Unexpected text after value"
+			return nil, parseErr
+		}
+		if _, ok := tables[current][key]; ok {
+			parseErr.Message = "Duplicate key " + key
+			return nil, parseErr
+		}
+		tables[current][key] = val
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+func parseKey(s string) (string, string, error) {
+	if strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'") {
+		return parseString(s)
+	}
+	i := 0
+	for i < len(s) && isBareKeyChar(s[i]) {
+		i++
+	}
+	if i == 0 {
+		return "", "", fmt.Errorf("Invalid key")
+	}
+	return s[:i], s[i:], nil
+}
+
+func parseValue(s string) (interface{}, string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'"):
+		return parseString(s)
+	case strings.HasPrefix(s, "["):
+		var arr []string
+		s = strings.TrimSpace(s[1:])
+		for !strings.HasPrefix(s, "]") {
+			v, rest, err := parseString(s)
+			if err != nil {
+				return nil, "", fmt.Errorf("Arrays may only contain strings")
+			}
+			arr = append(arr, v)
+			s = strings.TrimSpace(rest)
+			if strings.HasPrefix(s, ",") {
+				s = strings.TrimSpace(s[1:])
+			} else if !strings.HasPrefix(s, "]") {
+				return nil, "", fmt.Errorf("Expected , or ] in array")
+			}
+		}
+		if arr == nil {
+			arr = []string{}
+		}
+		return arr, s[1:], nil
+	}
+
+	end := strings.IndexAny(s, " \t#")
+	if end < 0 {
+		end = len(s)
+	}
+	word, rest := s[:end], s[end:]
+	switch word {
+	case "true":
+		return true, rest, nil
+	case "false":
+		return false, rest, nil
+	}
+	n, err := strconv.ParseInt(strings.Replace(word, "_", "", -1), 10, 64)
+	if err != nil {
+		return nil, "", fmt.Errorf("Unrecognized value %s", word)
+	}
+	return n, rest, nil
+}
+
+func parseString(s string) (string, string, error) {
+	if strings.HasPrefix(s, "'") {
+		end := strings.Index(s[1:], "'")
+		if end < 0 {
+			return "", "", fmt.Errorf("Unterminated string")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	}
+	if !strings.HasPrefix(s, `"`) {
+		return "", "", fmt.Errorf("Expected string")
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			v, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("Invalid string %s", s[:i+1])
+			}
+			return v, s[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("Unterminated string")
+}
+
+func isComment(s string) bool {
+	s = strings.TrimSpace(s)
+	return s == "" || strings.HasPrefix(s, "#")
+}
+
+func isBareKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isBareKeyChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
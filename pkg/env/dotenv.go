@@ -0,0 +1,181 @@
+package env
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type DotenvParseError struct {
+	File     string
+	LineNum  uint
+	LineText string
+	Message  string
+}
+
+func (e *DotenvParseError) Error() string {
+	return fmt.Sprintf("%s:%d: %s\n  %s", e.File, e.LineNum, e.Message, e.LineText)
+}
+
+// LoadDotenv adds the variables in the dotenv files to e. Variables already
+// in e take precedence over the files, and later files take precedence over
+// earlier ones.
+func (e Env) LoadDotenv(filenames ...string) error {
+	dot := Env{}
+	for _, filename := range filenames {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		err = dot.ReadDotenv(f, filename, e)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	for k, v := range dot {
+		if _, ok := e[k]; !ok {
+			e[k] = v
+		}
+	}
+	return nil
+}
+
+// ReadDotenv sets the variables in the dotenv file r, named file, in e.
+// Lines are of the form KEY=value, optionally preceded by "export". Values
+// may be single quoted, taken literally, or double quoted, with \n, \t, \",
+// \\ and \$ escapes. $VAR and ${VAR} in unquoted and double quoted values are
+// replaced by the value of VAR in parent, or in e if parent does not have it.
+// Comments start with # at the beginning of a line or after whitespace.
+func (e Env) ReadDotenv(r io.Reader, file string, parent Env) error {
+	lookup := func(name string) string {
+		if v, ok := parent[name]; ok {
+			return v
+		}
+		return e[name]
+	}
+
+	s := bufio.NewScanner(r)
+	ln := uint(0)
+	for s.Scan() {
+		ln++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parseErr := &DotenvParseError{File: file, LineNum: ln, LineText: line}
+
+		if strings.HasPrefix(line, "export ") {
+			line = strings.TrimSpace(line[len("export "):])
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			parseErr.Message = "Expected KEY=value"
+			return parseErr
+		}
+		key := strings.TrimSpace(line[:i])
+		if !isVarName(key) {
+			parseErr.Message = "Invalid variable name"
+			return parseErr
+		}
+
+		val, err := parseDotenvValue(line[i+1:], lookup)
+		if err != "" {
+			parseErr.Message = err
+			return parseErr
+		}
+		e[key] = val
+	}
+	return s.Err()
+}
+
+// parseDotenvValue returns the value of v, the text after the =, or a message
+// describing why it is invalid.
+func parseDotenvValue(v string, lookup func(string) string) (string, string) {
+	// whitespace before a # starts a comment even when there is no value
+	if t := strings.TrimLeft(v, " \t"); t != v && strings.HasPrefix(t, "#") {
+		return "", ""
+	}
+	v = strings.TrimSpace(v)
+
+	switch {
+	case strings.HasPrefix(v, "'"):
+		end := strings.Index(v[1:], "'")
+		if end < 0 {
+			return "", "Unterminated single quoted value"
+		}
+		if !isComment(v[end+2:]) {
+			return "", "Unexpected text after quoted value"
+		}
+		return v[1 : end+1], ""
+
+	case strings.HasPrefix(v, `"`):
+		var b []byte
+		for i := 1; i < len(v); i++ {
+			switch c := v[i]; c {
+			case '\\':
+				i++
+				if i == len(v) {
+					return "", "Unterminated double quoted value"
+				}
+				switch v[i] {
+				case 'n':
+					b = append(b, '\n')
+				case 't':
+					b = append(b, '\t')
+				case '$':
+					// keep the $ escaped through expansion
+					b = append(b, '$', '$')
+				default:
+					b = append(b, v[i])
+				}
+			case '"':
+				if !isComment(v[i+1:]) {
+					return "", "Unexpected text after quoted value"
+				}
+				return expand(string(b), lookup), ""
+			default:
+				b = append(b, c)
+			}
+		}
+		return "", "Unterminated double quoted value"
+	}
+
+	for i := 1; i < len(v); i++ {
+		if v[i] == '#' && (v[i-1] == ' ' || v[i-1] == '\t') {
+			v = strings.TrimSpace(v[:i])
+			break
+		}
+	}
+	return expand(v, lookup), ""
+}
+
+// expand replaces $VAR and ${VAR} in s, and $$ with $.
+func expand(s string, lookup func(string) string) string {
+	return os.Expand(s, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		return lookup(name)
+	})
+}
+
+func isComment(s string) bool {
+	s = strings.TrimSpace(s)
+	return s == "" || strings.HasPrefix(s, "#")
+}
+
+func isVarName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
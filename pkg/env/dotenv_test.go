@@ -0,0 +1,89 @@
+package env_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/nitrous-io/goop/pkg/env"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("dotenv", func() {
+	var e env.Env
+
+	BeforeEach(func() {
+		e = env.Env{}
+	})
+
+	Describe("ReadDotenv()", func() {
+		It("reads variables", func() {
+			err := e.ReadDotenv(bytes.NewBufferString(`
+				# database settings
+				DB_HOST=localhost
+				export DB_PORT = 5432 # default port
+				DB_URL="postgres://${DB_USER}@$DB_HOST:$DB_PORT/app"
+				GREETING='hello $USER # literally'
+				MULTI="a\nb\t\"c\" \$HOME"
+				EMPTY=
+				COMMENTED= # nothing to see
+				TABBED=on	# comment
+				HASH=#value
+			`), ".env", env.Env{"DB_USER": "goop"})
+			Expect(err).To(BeNil())
+			Expect(e).To(Equal(env.Env{
+				"DB_HOST":   "localhost",
+				"DB_PORT":   "5432",
+				"DB_URL":    "postgres://goop@localhost:5432/app",
+				"GREETING":  "hello $USER # literally",
+				"MULTI":     "a\nb\t\"c\" $HOME",
+				"EMPTY":     "",
+				"COMMENTED": "",
+				"TABBED":    "on",
+				"HASH":      "#value",
+			}))
+		})
+
+		It("expands variables from the parent env first", func() {
+			err := e.ReadDotenv(bytes.NewBufferString("HOST=dotenv\nURL=http://$HOST/"), ".env", env.Env{"HOST": "real"})
+			Expect(err).To(BeNil())
+			Expect(e["URL"]).To(Equal("http://real/"))
+		})
+
+		It("fails for malformed lines", func() {
+			for _, line := range []string{"NOVALUE", "1KEY=value", "KEY='unterminated", `KEY="unterminated`, `KEY="a" b`} {
+				err := e.ReadDotenv(bytes.NewBufferString(line), ".env", env.Env{})
+				Expect(err).To(BeAssignableToTypeOf(&env.DotenvParseError{}))
+				Expect(err.(*env.DotenvParseError).LineNum).To(Equal(uint(1)))
+			}
+		})
+	})
+
+	Describe("LoadDotenv()", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "goop")
+			Expect(err).To(BeNil())
+			Expect(ioutil.WriteFile(path.Join(dir, ".env"), []byte("PORT=3000\nHOST=localhost\nMODE=dev\n"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(path.Join(dir, ".env.local"), []byte("PORT=4000\n"), 0644)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("gives the env precedence over later files over earlier files", func() {
+			e = env.Env{"MODE": "prod"}
+			Expect(e.LoadDotenv(path.Join(dir, ".env"), path.Join(dir, ".env.local"))).To(Succeed())
+			Expect(e).To(Equal(env.Env{"MODE": "prod", "PORT": "4000", "HOST": "localhost"}))
+		})
+
+		It("fails for missing files", func() {
+			Expect(e.LoadDotenv(path.Join(dir, ".env.missing"))).NotTo(Succeed())
+		})
+	})
+})
@@ -3,6 +3,8 @@ package env
 import (
 	"bytes"
 	"os"
+	"sort"
+	"strings"
 )
 
 type Env map[string]string
@@ -24,19 +26,90 @@ func NewEnv() Env {
 	return e
 }
 
+// ListSeparator separates the entries of path lists such as PATH and GOPATH.
+var ListSeparator = string(os.PathListSeparator)
+
+// Strings returns the env in the form key=value, sorted by key.
 func (e Env) Strings() []string {
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
 	s := make([]string, 0, len(e))
-	for k, v := range e {
-		s = append(s, k+"="+v)
+	for _, k := range keys {
+		s = append(s, k+"="+e[k])
 	}
 	return s
 }
 
+// Prepend adds val to the start of the path list in key, removing any other
+// occurrences of it.
 func (e Env) Prepend(key string, val string) {
-	oldv := e[key]
-	if oldv == "" {
-		e[key] = val
-		return
+	list := e.list(key, val)
+	e[key] = strings.Join(append([]string{val}, list...), ListSeparator)
+}
+
+// Append adds val to the end of the path list in key, unless it is already
+// in the list.
+func (e Env) Append(key string, val string) {
+	list := e.list(key, "")
+	for _, v := range list {
+		if v == val {
+			return
+		}
+	}
+	e[key] = strings.Join(append(list, val), ListSeparator)
+}
+
+// list returns the entries of the path list in key, except for omit.
+func (e Env) list(key string, omit string) []string {
+	var list []string
+	if e[key] == "" {
+		return list
+	}
+	for _, v := range strings.Split(e[key], ListSeparator) {
+		if v != omit {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func (e Env) Unset(key string) {
+	delete(e, key)
+}
+
+// Diff describes how e differs from parent, one variable per line, sorted by
+// key: "+KEY=value" for added variables, "-KEY" for removed ones and
+// "~KEY=value (was old)" for changed ones.
+func (e Env) Diff(parent Env) []string {
+	keys := map[string]struct{}{}
+	for k := range e {
+		keys[k] = struct{}{}
+	}
+	for k := range parent {
+		keys[k] = struct{}{}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var diff []string
+	for _, k := range sorted {
+		v, ok := e[k]
+		old, inParent := parent[k]
+		switch {
+		case ok && !inParent:
+			diff = append(diff, "+"+k+"="+v)
+		case !ok && inParent:
+			diff = append(diff, "-"+k)
+		case v != old:
+			diff = append(diff, "~"+k+"="+v+" (was "+old+")")
+		}
 	}
-	e[key] = val + ":" + oldv
+	return diff
 }
@@ -46,6 +46,11 @@ var _ = Describe("env", func() {
 			Expect(s).To(ContainElement("_GOOP_ENV_TEST_BAR=bar=bar bar"))
 			Expect(s).To(ContainElement("_GOOP_ENV_TEST_EMPTY="))
 		})
+
+		It("is sorted by key", func() {
+			e = env.Env{"PATH": "/bin", "GOPATH": "/go", "HOME": "/root"}
+			Expect(e.Strings()).To(Equal([]string{"GOPATH=/go", "HOME=/root", "PATH=/bin"}))
+		})
 	})
 
 	Describe("Prepend()", func() {
@@ -79,5 +84,82 @@ var _ = Describe("env", func() {
 				Expect(e["_GOOP_ENV_TEST_EMPTY"]).To(Equal("lol:foo"))
 			})
 		})
+
+		Context("when the value is already in the list", func() {
+			It("moves it to the front", func() {
+				e["PATH"] = "/usr/bin:/project/.vendor/bin:/bin"
+				e.Prepend("PATH", "/project/.vendor/bin")
+				Expect(e["PATH"]).To(Equal("/project/.vendor/bin:/usr/bin:/bin"))
+				e.Prepend("PATH", "/project/.vendor/bin")
+				Expect(e["PATH"]).To(Equal("/project/.vendor/bin:/usr/bin:/bin"))
+			})
+		})
+	})
+
+	Describe("Append()", func() {
+		It("adds new value to the end of the list", func() {
+			e.Append("_GOOP_ENV_TEST_FOO", "lol")
+			Expect(e["_GOOP_ENV_TEST_FOO"]).To(Equal("foo:lol"))
+			e.Append("_GOOP_ENV_TEST_EMPTY", "lol")
+			Expect(e["_GOOP_ENV_TEST_EMPTY"]).To(Equal("lol"))
+		})
+
+		It("leaves the list alone if the value is already in it", func() {
+			e["GOPATH"] = "/project/.vendor:/go"
+			e.Append("GOPATH", "/project/.vendor")
+			Expect(e["GOPATH"]).To(Equal("/project/.vendor:/go"))
+		})
+	})
+
+	Describe("Unset()", func() {
+		It("removes the variable", func() {
+			e.Unset("_GOOP_ENV_TEST_FOO")
+			_, ok := e["_GOOP_ENV_TEST_FOO"]
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("Diff()", func() {
+		It("describes added, removed and changed variables", func() {
+			parent := env.Env{"GOPATH": "/go", "HOME": "/root", "GOOP_ENV": "/old/.vendor"}
+			e = env.Env{"GOPATH": "/project/.vendor:/go", "HOME": "/root", "GOBIN": "/project/.vendor/bin"}
+			Expect(e.Diff(parent)).To(Equal([]string{
+				"+GOBIN=/project/.vendor/bin",
+				"-GOOP_ENV",
+				"~GOPATH=/project/.vendor:/go (was /go)",
+			}))
+		})
+	})
+
+	Describe("Vendor()", func() {
+		BeforeEach(func() {
+			e = env.Env{"GOPATH": "/go", "PATH": "/bin"}
+		})
+
+		It("prepends the vendor directory and sets GOBIN", func() {
+			v := e.Vendor("/project/.vendor", false)
+			Expect(v).To(Equal(env.Env{"GOPATH": "/project/.vendor:/go", "GOBIN": "/project/.vendor/bin", "PATH": "/project/.vendor/bin:/bin"}))
+			Expect(e["GOPATH"]).To(Equal("/go"))
+		})
+
+		It("replaces GOPATH when isolated", func() {
+			Expect(e.Vendor("/project/.vendor", true)["GOPATH"]).To(Equal("/project/.vendor"))
+		})
+
+		It("undoes the changes of goop env first", func() {
+			e = env.Env{"GOPATH": "/old/.vendor:/go", "GOBIN": "/old/.vendor/bin", "PATH": "/old/.vendor/bin:/bin", "GOOP_ENV": "/old/.vendor", "GOOP_OLD_GOPATH": "/go", "GOOP_OLD_PATH": "/bin"}
+			Expect(e.Vendor("/project/.vendor", false)).To(Equal(env.Env{"GOPATH": "/project/.vendor:/go", "GOBIN": "/project/.vendor/bin", "PATH": "/project/.vendor/bin:/bin"}))
+		})
+	})
+
+	Describe("Restore()", func() {
+		It("leaves an env not changed by goop env alone", func() {
+			Expect(e.Restore()).To(Equal(e))
+		})
+
+		It("unsets variables that were not set before", func() {
+			e = env.Env{"GOBIN": "/project/.vendor/bin", "GOOP_ENV": "/project/.vendor"}
+			Expect(e.Restore()).To(BeEmpty())
+		})
 	})
 })
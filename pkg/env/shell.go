@@ -0,0 +1,101 @@
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Shells are the shells WriteShell can write statements for.
+var Shells = []string{"bash", "zsh", "fish", "powershell", "json"}
+
+type UnsupportedShellError struct {
+	Shell string
+}
+
+func (e *UnsupportedShellError) Error() string {
+	return fmt.Sprintf("unsupported shell %q; use one of %s", e.Shell, strings.Join(Shells, ", "))
+}
+
+// WriteShell writes statements in the syntax of shell that set the variables
+// in set and unset the variables in unset. The json "shell" writes an object
+// instead, with null for unset variables.
+func WriteShell(w io.Writer, shell string, set Env, unset []string) error {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	unset = append([]string{}, unset...)
+	sort.Strings(unset)
+
+	var lines []string
+	switch shell {
+	case "bash", "zsh":
+		for _, k := range keys {
+			lines = append(lines, "export "+k+"="+quotePOSIX(set[k]))
+		}
+		for _, k := range unset {
+			lines = append(lines, "unset "+k)
+		}
+	case "fish":
+		for _, k := range keys {
+			// fish keeps variables ending in PATH as lists
+			vals := []string{set[k]}
+			if strings.HasSuffix(k, "PATH") {
+				vals = strings.Split(set[k], ListSeparator)
+			}
+			for i, v := range vals {
+				vals[i] = quoteFish(v)
+			}
+			lines = append(lines, "set -gx "+k+" "+strings.Join(vals, " ")+";")
+		}
+		for _, k := range unset {
+			lines = append(lines, "set -e "+k+";")
+		}
+	case "powershell":
+		for _, k := range keys {
+			lines = append(lines, "$Env:"+k+" = "+quotePowerShell(set[k]))
+		}
+		for _, k := range unset {
+			lines = append(lines, "Remove-Item Env:"+k+" -ErrorAction SilentlyContinue")
+		}
+	case "json":
+		m := map[string]*string{}
+		for _, k := range keys {
+			v := set[k]
+			m[k] = &v
+		}
+		for _, k := range unset {
+			m[k] = nil
+		}
+		b, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return err
+		}
+		lines = append(lines, string(b))
+	default:
+		return &UnsupportedShellError{Shell: shell}
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func quotePOSIX(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func quoteFish(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return "'" + strings.Replace(s, "'", `\'`, -1) + "'"
+}
+
+func quotePowerShell(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
@@ -0,0 +1,55 @@
+package env_test
+
+import (
+	"bytes"
+
+	"github.com/nitrous-io/goop/pkg/env"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WriteShell()", func() {
+	var (
+		buf *bytes.Buffer
+		set env.Env
+	)
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+		set = env.Env{"GOPATH": "/it's/.vendor:/go", "GOBIN": `C:\bin`}
+	})
+
+	It("writes quoted exports for bash and zsh", func() {
+		Expect(env.WriteShell(buf, "bash", set, []string{"GOOP_OLD_GOBIN"})).To(Succeed())
+		Expect(buf.String()).To(Equal(`export GOBIN='C:\bin'
+export GOPATH='/it'\''s/.vendor:/go'
+unset GOOP_OLD_GOBIN
+`))
+	})
+
+	It("writes lists for fish path variables", func() {
+		Expect(env.WriteShell(buf, "fish", set, []string{"GOOP_OLD_GOBIN"})).To(Succeed())
+		Expect(buf.String()).To(Equal(`set -gx GOBIN 'C:\\bin';
+set -gx GOPATH '/it\'s/.vendor' '/go';
+set -e GOOP_OLD_GOBIN;
+`))
+	})
+
+	It("writes assignments for powershell", func() {
+		Expect(env.WriteShell(buf, "powershell", set, []string{"GOOP_OLD_GOBIN"})).To(Succeed())
+		Expect(buf.String()).To(Equal(`$Env:GOBIN = 'C:\bin'
+$Env:GOPATH = '/it''s/.vendor:/go'
+Remove-Item Env:GOOP_OLD_GOBIN -ErrorAction SilentlyContinue
+`))
+	})
+
+	It("writes json with null for unset variables", func() {
+		Expect(env.WriteShell(buf, "json", set, []string{"GOOP_OLD_GOBIN"})).To(Succeed())
+		Expect(buf.String()).To(MatchJSON(`{"GOBIN": "C:\\bin", "GOPATH": "/it's/.vendor:/go", "GOOP_OLD_GOBIN": null}`))
+	})
+
+	It("fails for other shells", func() {
+		err := env.WriteShell(buf, "tcsh", set, nil)
+		Expect(err).To(BeAssignableToTypeOf(&env.UnsupportedShellError{}))
+	})
+})
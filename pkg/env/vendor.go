@@ -0,0 +1,71 @@
+package env
+
+import "path"
+
+// VendorVars are the variables Vendor changes.
+var VendorVars = []string{"GOPATH", "GOBIN", "PATH"}
+
+// goop env and goop shell mark the environments they set up differently:
+// evaluating the output of goop env sets ActiveVar to the vendor directory,
+// which Restore uses to undo the changes, and goop shell sets ShellVar to the
+// project directory for prompts to show. goop shell undoes the changes made
+// by goop env like goop exec does, but refuses to start while either marker
+// is set, so that exiting it always returns to an environment without goop.
+const (
+	// ActiveVar is set to the vendor directory by evaluating the output of
+	// goop env.
+	ActiveVar = "GOOP_ENV"
+
+	// ShellVar is set to the project directory in shells started by goop
+	// shell.
+	ShellVar = "GOOP_ACTIVE"
+
+	// OldPrefix prefixes the variables goop env saves the previous values
+	// of VendorVars in.
+	OldPrefix = "GOOP_OLD_"
+)
+
+// Vendor returns a copy of e set up to use the packages installed in
+// vendorDir: GOPATH starts with vendorDir (or is just vendorDir if isolated),
+// GOBIN is its bin directory, and PATH starts with it. Changes made by
+// evaluating the output of goop env are undone first, so that they do not
+// stack.
+func (e Env) Vendor(vendorDir string, isolated bool) Env {
+	v := e.Restore()
+	binPath := path.Join(vendorDir, "bin")
+	if isolated {
+		v["GOPATH"] = vendorDir
+	} else {
+		v.Prepend("GOPATH", vendorDir)
+	}
+	v["GOBIN"] = binPath
+	v.Prepend("PATH", binPath)
+	return v
+}
+
+// Restore returns a copy of e with the changes made by evaluating the output
+// of goop env undone.
+func (e Env) Restore() Env {
+	r := e.Copy()
+	if r[ActiveVar] == "" {
+		return r
+	}
+	for _, k := range VendorVars {
+		if v, ok := r[OldPrefix+k]; ok {
+			r[k] = v
+		} else {
+			r.Unset(k)
+		}
+		r.Unset(OldPrefix + k)
+	}
+	r.Unset(ActiveVar)
+	return r
+}
+
+func (e Env) Copy() Env {
+	c := make(Env, len(e))
+	for k, v := range e {
+		c[k] = v
+	}
+	return c
+}